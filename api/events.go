@@ -0,0 +1,133 @@
+package api
+
+import (
+	"encoding/json"
+	"slices"
+	"time"
+
+	"sektron/engine"
+	"sektron/sequencer"
+)
+
+const (
+	// eventsFrequency is how often the sequencer state is checked for
+	// changes to stream. It's short enough to follow the playhead at high
+	// tempos.
+	eventsFrequency = 10 * time.Millisecond
+
+	// Events are dropped for the clients that can't keep up.
+	eventsBufferSize = 256
+)
+
+// Event represents a sequencer state change streamed to the websocket
+// clients:
+//   - "state" holds the whole state, sent on connection
+//   - "transport" is sent on play and stop
+//   - "tempo" is sent on tempo changes
+//   - "pattern" is sent when the active pattern or the chain changes
+//   - "playhead" holds the current step of each track, -1 if muted
+//   - "trigger" is sent when a step is triggered, i.e. its notes are sent. The
+//     steps skipped because of their probability aren't sent
+type Event struct {
+	Type     string        `json:"type"`
+	State    *engine.State `json:"state,omitempty"`
+	Playing  *bool         `json:"playing,omitempty"`
+	Tempo    float64       `json:"tempo,omitempty"`
+	Pattern  *int          `json:"pattern,omitempty"`
+	Chain    []int         `json:"chain,omitempty"`
+	Tracks   []int         `json:"tracks,omitempty"`
+	Track    *int          `json:"track,omitempty"`
+	Step     *int          `json:"step,omitempty"`
+	Chord    []int         `json:"chord,omitempty"`
+	Velocity uint8         `json:"velocity,omitempty"`
+}
+
+// subscribe registers a new events client.
+func (s *Server) subscribe() chan []byte {
+	events := make(chan []byte, eventsBufferSize)
+	s.subscribersMu.Lock()
+	defer s.subscribersMu.Unlock()
+	s.subscribers[events] = struct{}{}
+	return events
+}
+
+func (s *Server) unsubscribe(events chan []byte) {
+	s.subscribersMu.Lock()
+	defer s.subscribersMu.Unlock()
+	delete(s.subscribers, events)
+}
+
+func (s *Server) publish(events []Event) {
+	s.subscribersMu.Lock()
+	defer s.subscribersMu.Unlock()
+	for _, event := range events {
+		content, err := json.Marshal(event)
+		if err != nil {
+			continue
+		}
+		for subscriber := range s.subscribers {
+			select {
+			case subscriber <- content:
+			default:
+			}
+		}
+	}
+}
+
+// watch compares the sequencer state with the previous one and publishes
+// the changes.
+func (s *Server) watch() {
+	ticker := time.NewTicker(eventsFrequency)
+	defer ticker.Stop()
+	s.seq.Lock()
+	previous := engine.CurrentState(s.seq)
+	s.seq.Unlock()
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+		}
+
+		s.seq.Lock()
+		state := engine.CurrentState(s.seq)
+		var events []Event
+		if state.Playing != previous.Playing {
+			events = append(events, Event{Type: "transport", Playing: &state.Playing})
+		}
+		if state.Tempo != previous.Tempo {
+			events = append(events, Event{Type: "tempo", Tempo: state.Tempo})
+		}
+		if state.Pattern != previous.Pattern || !slices.Equal(state.Chain, previous.Chain) {
+			events = append(events, Event{Type: "pattern", Pattern: &state.Pattern, Chain: state.Chain})
+		}
+		if !slices.Equal(state.Tracks, previous.Tracks) {
+			events = append(events, Event{Type: "playhead", Tracks: state.Tracks})
+		}
+		s.seq.Unlock()
+
+		previous = state
+		if len(events) > 0 {
+			s.publish(events)
+		}
+	}
+}
+
+// publishTrigger publishes the trigger event of the given step. It's called
+// by the sequencer, while triggering the step (check
+// sequencer.OnTrigger).
+func (s *Server) publishTrigger(track int, step sequencer.Step) {
+	position := step.Position()
+	// []uint8 would be encoded as a base64 string.
+	var chord []int
+	for _, note := range step.Chord() {
+		chord = append(chord, int(note))
+	}
+	s.publish([]Event{{
+		Type:     "trigger",
+		Track:    &track,
+		Step:     &position,
+		Chord:    chord,
+		Velocity: step.Velocity(),
+	}})
+}
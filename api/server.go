@@ -0,0 +1,385 @@
+// Package api provides a local HTTP server exposing the bank and the active
+// pattern, and streaming the sequencer events over a websocket, for browser
+// visualizers or remotes. Patterns, tracks and steps are numbered from 0, as
+// in the Sequencer interface.
+//
+//	GET    /api/state                         sequencer state (check engine.State)
+//	POST   /api/play, /api/stop               start or stop playing
+//	PUT    /api/tempo                         set the tempo, e.g. 120.5
+//	GET    /api/patterns                      bank patterns and active pattern
+//	GET    /api/patterns/{n}                  pattern
+//	PUT    /api/patterns/{n}                  replace a pattern
+//	DELETE /api/patterns/{n}                  clear a pattern
+//	POST   /api/patterns/{n}/load             switch to a pattern (at the end of the current one when playing)
+//	POST   /api/patterns/{n}/chain            chain a pattern
+//	GET    /api/pattern, PUT /api/pattern     active pattern
+//	POST   /api/tracks/{t}/toggle             mute or unmute a track
+//	POST   /api/tracks/{t}/steps/{s}/toggle   activate or deactivate a step
+//	GET    /api/events                        websocket events stream (check Event)
+//
+// Requests and responses bodies are json. Modifying requests reply with the
+// sequencer state. Requests are applied while holding the sequencer lock.
+//
+// Browsers can only use the api from the allowed origins, and requests must
+// address the server by ip, localhost or the host of an allowed origin
+// (check checkOrigin).
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"sektron/engine"
+	"sektron/filesystem"
+	"sektron/sequencer"
+)
+
+// Server serves the api over HTTP.
+type Server struct {
+	seq     sequencer.Sequencer
+	origins []string
+	server  *http.Server
+	done    chan struct{}
+
+	// stopTriggers stops publishing the trigger events.
+	stopTriggers func()
+
+	subscribersMu sync.Mutex
+	subscribers   map[chan []byte]struct{}
+}
+
+// Listen starts the HTTP server on the given address, e.g. ":8080". The
+// browser pages of the given origins are allowed to use the api.
+func Listen(address string, origins []string, seq sequencer.Sequencer) (*Server, error) {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, err
+	}
+	s := &Server{
+		seq:         seq,
+		origins:     origins,
+		done:        make(chan struct{}),
+		subscribers: map[chan []byte]struct{}{},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/state", s.handleState)
+	mux.HandleFunc("POST /api/play", s.handlePlay)
+	mux.HandleFunc("POST /api/stop", s.handleStop)
+	mux.HandleFunc("PUT /api/tempo", s.handleTempo)
+	mux.HandleFunc("GET /api/patterns", s.handleBank)
+	mux.HandleFunc("GET /api/patterns/{pattern}", s.handleGetPattern)
+	mux.HandleFunc("PUT /api/patterns/{pattern}", s.handleSetPattern)
+	mux.HandleFunc("DELETE /api/patterns/{pattern}", s.handleClearPattern)
+	mux.HandleFunc("POST /api/patterns/{pattern}/load", s.handleLoad)
+	mux.HandleFunc("POST /api/patterns/{pattern}/chain", s.handleChain)
+	mux.HandleFunc("GET /api/pattern", s.handleGetPattern)
+	mux.HandleFunc("PUT /api/pattern", s.handleSetPattern)
+	mux.HandleFunc("POST /api/tracks/{track}/toggle", s.handleToggleTrack)
+	mux.HandleFunc("POST /api/tracks/{track}/steps/{step}/toggle", s.handleToggleStep)
+	mux.HandleFunc("GET /api/events", s.handleEvents)
+
+	s.server = &http.Server{Handler: s.checkOrigin(mux)}
+	go func() {
+		if err := s.server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("api: %s", err)
+		}
+	}()
+	go s.watch()
+	seq.Lock()
+	s.stopTriggers = seq.OnTrigger(s.publishTrigger)
+	seq.Unlock()
+	return s, nil
+}
+
+// Close stops the server.
+func (s *Server) Close() error {
+	s.seq.Lock()
+	s.stopTriggers()
+	s.seq.Unlock()
+	close(s.done)
+	return s.server.Close()
+}
+
+// checkOrigin rejects the requests sent by the browser pages of the origins
+// that aren't allowed, so that any page can't control the sequencer, and
+// answers the CORS requests of the allowed ones. The websocket handshake is
+// checked too, as browsers don't apply CORS to websockets. Clients that
+// aren't browsers don't send an origin.
+//
+// The requests addressing the server by another host name are rejected as
+// well: a page can make its own host name resolve to the server address (DNS
+// rebinding), its requests being then same origin, without an Origin header
+// for the GET ones.
+func (s *Server) checkOrigin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !s.isAllowedHost(r.Host) {
+			fail(w, http.StatusForbidden, fmt.Errorf("host %s not allowed", r.Host))
+			return
+		}
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if !slices.Contains(s.origins, origin) {
+			fail(w, http.StatusForbidden, fmt.Errorf("origin %s not allowed", origin))
+			return
+		}
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Add("Vary", "Origin")
+		if r.Method == http.MethodOptions {
+			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE")
+			w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// isAllowedHost returns true if the given request host is an ip address,
+// localhost or the host of one of the allowed origins.
+func (s *Server) isAllowedHost(host string) bool {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.Trim(host, "[]")
+	if net.ParseIP(host) != nil || host == "localhost" {
+		return true
+	}
+	return slices.ContainsFunc(s.origins, func(origin string) bool {
+		u, err := url.Parse(origin)
+		return err == nil && u.Hostname() == host
+	})
+}
+
+func (s *Server) handleState(w http.ResponseWriter, r *http.Request) {
+	s.seq.Lock()
+	defer s.seq.Unlock()
+	reply(w, engine.CurrentState(s.seq))
+}
+
+func (s *Server) handlePlay(w http.ResponseWriter, r *http.Request) {
+	s.seq.Lock()
+	defer s.seq.Unlock()
+	if !s.seq.IsPlaying() {
+		s.seq.TogglePlay()
+	}
+	reply(w, engine.CurrentState(s.seq))
+}
+
+func (s *Server) handleStop(w http.ResponseWriter, r *http.Request) {
+	s.seq.Lock()
+	defer s.seq.Unlock()
+	if s.seq.IsPlaying() {
+		s.seq.TogglePlay()
+	}
+	reply(w, engine.CurrentState(s.seq))
+}
+
+func (s *Server) handleTempo(w http.ResponseWriter, r *http.Request) {
+	var tempo float64
+	if err := json.NewDecoder(r.Body).Decode(&tempo); err != nil {
+		fail(w, http.StatusBadRequest, err)
+		return
+	}
+	s.seq.Lock()
+	defer s.seq.Unlock()
+	s.seq.SetTempo(tempo)
+	reply(w, engine.CurrentState(s.seq))
+}
+
+// bank represents the bank patterns. The active pattern includes the unsaved
+// edits.
+type bank struct {
+	Active   int                  `json:"active"`
+	Patterns []filesystem.Pattern `json:"patterns"`
+}
+
+func (s *Server) handleBank(w http.ResponseWriter, r *http.Request) {
+	s.seq.Lock()
+	defer s.seq.Unlock()
+	b := bank{Active: s.seq.ActivePattern()}
+	for i := range s.seq.Patterns() {
+		b.Patterns = append(b.Patterns, s.seq.Pattern(i))
+	}
+	reply(w, b)
+}
+
+func (s *Server) handleGetPattern(w http.ResponseWriter, r *http.Request) {
+	s.seq.Lock()
+	defer s.seq.Unlock()
+	pattern, err := s.pattern(r)
+	if err != nil {
+		fail(w, http.StatusNotFound, err)
+		return
+	}
+	reply(w, s.seq.Pattern(pattern))
+}
+
+func (s *Server) handleSetPattern(w http.ResponseWriter, r *http.Request) {
+	var p filesystem.Pattern
+	if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+		fail(w, http.StatusBadRequest, err)
+		return
+	}
+	s.seq.Lock()
+	defer s.seq.Unlock()
+	pattern, err := s.pattern(r)
+	if err != nil {
+		fail(w, http.StatusNotFound, err)
+		return
+	}
+	if err := s.seq.SetPattern(pattern, p); err != nil {
+		fail(w, http.StatusBadRequest, err)
+		return
+	}
+	reply(w, engine.CurrentState(s.seq))
+}
+
+func (s *Server) handleClearPattern(w http.ResponseWriter, r *http.Request) {
+	s.seq.Lock()
+	defer s.seq.Unlock()
+	pattern, err := s.pattern(r)
+	if err != nil {
+		fail(w, http.StatusNotFound, err)
+		return
+	}
+	s.seq.ClearPattern(pattern)
+	reply(w, engine.CurrentState(s.seq))
+}
+
+func (s *Server) handleLoad(w http.ResponseWriter, r *http.Request) {
+	s.seq.Lock()
+	defer s.seq.Unlock()
+	pattern, err := s.pattern(r)
+	if err != nil {
+		fail(w, http.StatusNotFound, err)
+		return
+	}
+	if s.seq.IsPlaying() {
+		s.seq.ChainNow(pattern)
+	} else {
+		s.seq.Save()
+		s.seq.Load(pattern)
+	}
+	reply(w, engine.CurrentState(s.seq))
+}
+
+func (s *Server) handleChain(w http.ResponseWriter, r *http.Request) {
+	s.seq.Lock()
+	defer s.seq.Unlock()
+	pattern, err := s.pattern(r)
+	if err != nil {
+		fail(w, http.StatusNotFound, err)
+		return
+	}
+	s.seq.Chain(pattern)
+	reply(w, engine.CurrentState(s.seq))
+}
+
+func (s *Server) handleToggleTrack(w http.ResponseWriter, r *http.Request) {
+	s.seq.Lock()
+	defer s.seq.Unlock()
+	track, err := index(r, "track", len(s.seq.Tracks()))
+	if err != nil {
+		fail(w, http.StatusNotFound, err)
+		return
+	}
+	s.seq.ToggleTrack(track)
+	reply(w, engine.CurrentState(s.seq))
+}
+
+func (s *Server) handleToggleStep(w http.ResponseWriter, r *http.Request) {
+	s.seq.Lock()
+	defer s.seq.Unlock()
+	track, err := index(r, "track", len(s.seq.Tracks()))
+	if err != nil {
+		fail(w, http.StatusNotFound, err)
+		return
+	}
+	step, err := index(r, "step", len(s.seq.Tracks()[track].Steps()))
+	if err != nil {
+		fail(w, http.StatusNotFound, err)
+		return
+	}
+	s.seq.ToggleStep(track, step)
+	reply(w, engine.CurrentState(s.seq))
+}
+
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	ws, err := upgrade(w, r)
+	if err != nil {
+		fail(w, http.StatusBadRequest, err)
+		return
+	}
+	events := s.subscribe()
+	defer s.unsubscribe(events)
+
+	s.seq.Lock()
+	state := engine.CurrentState(s.seq)
+	s.seq.Unlock()
+	content, _ := json.Marshal(Event{Type: "state", State: &state})
+	if err := ws.write(opText, content); err != nil {
+		ws.conn.Close()
+		return
+	}
+
+	closed := make(chan struct{})
+	go func() {
+		ws.serve()
+		close(closed)
+	}()
+	for {
+		select {
+		case <-closed:
+			return
+		case <-s.done:
+			ws.conn.Close()
+			return
+		case event := <-events:
+			if err := ws.write(opText, event); err != nil {
+				ws.conn.Close()
+				return
+			}
+		}
+	}
+}
+
+// pattern returns the pattern of the request path, or the active one.
+func (s *Server) pattern(r *http.Request) (int, error) {
+	if r.PathValue("pattern") == "" {
+		return s.seq.ActivePattern(), nil
+	}
+	return index(r, "pattern", len(s.seq.Patterns()))
+}
+
+// index returns the given path value as an index lower than count.
+func index(r *http.Request, name string, count int) (int, error) {
+	value, err := strconv.Atoi(r.PathValue(name))
+	if err != nil || value < 0 || value >= count {
+		return 0, fmt.Errorf("invalid %s %s", name, r.PathValue(name))
+	}
+	return value, nil
+}
+
+func reply(w http.ResponseWriter, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(value); err != nil {
+		log.Printf("api: %s", err)
+	}
+}
+
+func fail(w http.ResponseWriter, status int, err error) {
+	http.Error(w, err.Error(), status)
+}
@@ -0,0 +1,119 @@
+package engine
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"time"
+
+	"sektron/ui"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/x/term"
+)
+
+// resizeCheckFrequency is how often the attached terminal size is checked.
+// Polling is simpler than handling SIGWINCH and works on all platforms.
+const resizeCheckFrequency = 250 * time.Millisecond
+
+// attach runs a terminal ui session for a client until it detaches or
+// disconnects. The sequencer keeps playing either way.
+//
+// The sessions share the engine configuration: it's reloaded from the file
+// first, in case it was edited meanwhile, then each session saves its
+// changes to it while holding the sequencer lock.
+func (s *Server) attach(r io.Reader, conn net.Conn) error {
+	s.seq.Lock()
+	s.config.Reload()
+	s.seq.Unlock()
+
+	input, inputWriter := io.Pipe()
+	p := tea.NewProgram(
+		ui.NewDetachable(&s.config, s.seq),
+		tea.WithInput(input),
+		tea.WithOutput(conn),
+		tea.WithoutSignalHandler(),
+	)
+
+	go func() {
+		defer inputWriter.Close()
+		for {
+			kind, payload, err := readFrame(r)
+			if err != nil {
+				p.Quit()
+				return
+			}
+			switch {
+			case kind == frameInput:
+				inputWriter.Write(payload)
+			case kind == frameResize && len(payload) == 4:
+				p.Send(tea.WindowSizeMsg{
+					Width:  int(binary.BigEndian.Uint16(payload)),
+					Height: int(binary.BigEndian.Uint16(payload[2:])),
+				})
+			}
+		}
+	}()
+
+	_, err := p.Run()
+	return err
+}
+
+// Attach connects the terminal to the engine listening on the given socket
+// and runs a ui session until it's detached.
+func Attach(path string) error {
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if _, err := fmt.Fprintln(conn, attachCommand); err != nil {
+		return err
+	}
+
+	stdin := os.Stdin.Fd()
+	if !term.IsTerminal(stdin) {
+		return fmt.Errorf("stdin is not a terminal")
+	}
+	state, err := term.MakeRaw(stdin)
+	if err != nil {
+		return err
+	}
+	defer term.Restore(stdin, state)
+
+	frames := &frameWriter{w: conn}
+	go sendSize(frames, os.Stdout.Fd())
+	go func() {
+		buf := make([]byte, 1024)
+		for {
+			n, err := os.Stdin.Read(buf)
+			if err != nil {
+				return
+			}
+			if err := frames.write(frameInput, buf[:n]); err != nil {
+				return
+			}
+		}
+	}()
+
+	_, err = io.Copy(os.Stdout, conn)
+	return err
+}
+
+// sendSize sends the terminal size to the engine, then again each time it
+// changes.
+func sendSize(frames *frameWriter, fd uintptr) {
+	var width, height int
+	for {
+		w, h, err := term.GetSize(fd)
+		if err == nil && (w != width || h != height) {
+			width, height = w, h
+			if err := frames.resize(width, height); err != nil {
+				return
+			}
+		}
+		time.Sleep(resizeCheckFrequency)
+	}
+}
@@ -0,0 +1,386 @@
+package engine
+
+import (
+	"fmt"
+
+	"sektron/filesystem"
+	"sektron/sequencer"
+)
+
+// RangeArgs represents the steps of a track from first to last, included.
+type RangeArgs struct {
+	Track int `json:"track"`
+	First int `json:"first"`
+	Last  int `json:"last"`
+}
+
+// RotateArgs represents a track rotation by a number of steps, to the right
+// if positive or to the left if negative.
+type RotateArgs struct {
+	Track int `json:"track"`
+	Steps int `json:"steps"`
+}
+
+// RandomizeArgs represents the randomization of a track.
+type RandomizeArgs struct {
+	Track    int                   `json:"track"`
+	Settings filesystem.Randomizer `json:"settings"`
+}
+
+// ControlArgs represents a midi control of a track (check midi.Control).
+type ControlArgs struct {
+	Track   int `json:"track"`
+	Control int `json:"control"`
+}
+
+// Parameters holds the parameters to set on a track or a step. Missing
+// parameters are left unchanged. Controls are indexed by control number
+// (check midi.Control) and must be active on the track.
+type Parameters struct {
+	Chord       []uint8       `json:"chord,omitempty"`
+	Length      *int          `json:"length,omitempty"`
+	Velocity    *int          `json:"velocity,omitempty"`
+	Probability *int          `json:"probability,omitempty"`
+	Controls    map[int]int16 `json:"controls,omitempty"`
+}
+
+// TrackArgs represents the parameters to set on a track.
+type TrackArgs struct {
+	Track   int  `json:"track"`
+	Device  *int `json:"device,omitempty"`
+	Channel *int `json:"channel,omitempty"`
+	Parameters
+}
+
+// StepParamsArgs represents the parameters to set on a step.
+type StepParamsArgs struct {
+	Track  int  `json:"track"`
+	Step   int  `json:"step"`
+	Offset *int `json:"offset,omitempty"`
+	Parameters
+}
+
+// HistoryState represents the sequencer state after an undo or a redo, and
+// the description of the edit, empty if there was nothing to undo or redo.
+type HistoryState struct {
+	State
+	Edit string `json:"edit"`
+}
+
+// AddTrack adds a track to the active pattern.
+func (s *Service) AddTrack(_ struct{}, state *State) error {
+	return s.apply(state, func() error {
+		s.seq.AddTrack()
+		return nil
+	})
+}
+
+// RemoveTrack removes the last track of the active pattern.
+func (s *Service) RemoveTrack(_ struct{}, state *State) error {
+	return s.apply(state, func() error {
+		s.seq.RemoveTrack()
+		return nil
+	})
+}
+
+// ToggleTrack mutes or unmutes the given track.
+func (s *Service) ToggleTrack(track int, state *State) error {
+	return s.trackEdit(track, state, s.seq.ToggleTrack)
+}
+
+// SetTrack sets the given track parameters.
+func (s *Service) SetTrack(args TrackArgs, state *State) error {
+	return s.apply(state, func() error {
+		if err := s.checkTrack(args.Track); err != nil {
+			return err
+		}
+		track := s.seq.Tracks()[args.Track]
+		if args.Device != nil && (*args.Device < 0 || *args.Device >= len(s.seq.OutputDevices())) {
+			return fmt.Errorf("invalid device %d", *args.Device)
+		}
+		if args.Channel != nil && (*args.Channel < 0 || *args.Channel > 15) {
+			return fmt.Errorf("invalid channel %d", *args.Channel)
+		}
+		if err := args.check(track); err != nil {
+			return err
+		}
+		if args.Device != nil {
+			track.SetDevice(*args.Device)
+		}
+		if args.Channel != nil {
+			track.SetChannel(uint8(*args.Channel))
+		}
+		args.set(track)
+		return nil
+	})
+}
+
+// AddControl activates the given control on a track.
+func (s *Service) AddControl(args ControlArgs, state *State) error {
+	return s.controlEdit(args, state, func(track sequencer.Track) {
+		track.AddControl(args.Control)
+	})
+}
+
+// RemoveControl deactivates the given control on a track.
+func (s *Service) RemoveControl(args ControlArgs, state *State) error {
+	return s.controlEdit(args, state, func(track sequencer.Track) {
+		track.RemoveControl(args.Control)
+	})
+}
+
+// AddStep adds a step at the end of the given track.
+func (s *Service) AddStep(track int, state *State) error {
+	return s.trackEdit(track, state, s.seq.AddStep)
+}
+
+// RemoveStep removes the last step of the given track.
+func (s *Service) RemoveStep(track int, state *State) error {
+	return s.trackEdit(track, state, s.seq.RemoveStep)
+}
+
+// ToggleStep activates or deactivates the given step.
+func (s *Service) ToggleStep(args StepArgs, state *State) error {
+	return s.stepEdit(args, state, s.seq.ToggleStep)
+}
+
+// SetStep sets the given step parameters.
+func (s *Service) SetStep(args StepParamsArgs, state *State) error {
+	return s.apply(state, func() error {
+		if err := s.checkStep(args.Track, args.Step); err != nil {
+			return err
+		}
+		track := s.seq.Tracks()[args.Track]
+		step := track.Steps()[args.Step]
+		if !step.IsActive() {
+			// Like in the ui, the inactive steps can't be edited, their
+			// parameters being cleared when activated.
+			return fmt.Errorf("step %d is inactive", args.Step)
+		}
+		if args.Offset != nil && (*args.Offset < 0 || *args.Offset > 5) {
+			return fmt.Errorf("invalid offset %d", *args.Offset)
+		}
+		if err := args.check(track); err != nil {
+			return err
+		}
+		if args.Offset != nil {
+			step.SetOffset(*args.Offset)
+		}
+		args.set(step)
+		return nil
+	})
+}
+
+// CopyStep copies the given step to the clipboard.
+func (s *Service) CopyStep(args StepArgs, state *State) error {
+	return s.stepEdit(args, state, s.seq.CopyStep)
+}
+
+// PasteStep pastes the clipboard step to the given step.
+func (s *Service) PasteStep(args StepArgs, state *State) error {
+	return s.stepEdit(args, state, s.seq.PasteStep)
+}
+
+// CopySteps copies the given steps to the clipboard.
+func (s *Service) CopySteps(args RangeArgs, state *State) error {
+	return s.rangeEdit(args, state, s.seq.CopySteps)
+}
+
+// PasteSteps pastes the clipboard steps from the given step.
+func (s *Service) PasteSteps(args StepArgs, state *State) error {
+	return s.stepEdit(args, state, s.seq.PasteSteps)
+}
+
+// ClearSteps resets the given steps.
+func (s *Service) ClearSteps(args RangeArgs, state *State) error {
+	return s.rangeEdit(args, state, s.seq.ClearSteps)
+}
+
+// CopyTrack copies the given track to the clipboard.
+func (s *Service) CopyTrack(track int, state *State) error {
+	return s.trackEdit(track, state, s.seq.CopyTrack)
+}
+
+// PasteTrack pastes the clipboard track to the given track.
+func (s *Service) PasteTrack(track int, state *State) error {
+	return s.trackEdit(track, state, s.seq.PasteTrack)
+}
+
+// ClearTrack resets the given track.
+func (s *Service) ClearTrack(track int, state *State) error {
+	return s.trackEdit(track, state, s.seq.ClearTrack)
+}
+
+// CopyPattern copies the given pattern to the clipboard.
+func (s *Service) CopyPattern(pattern int, state *State) error {
+	return s.patternEdit(pattern, state, s.seq.CopyPattern)
+}
+
+// PastePattern pastes the clipboard pattern to the given pattern.
+func (s *Service) PastePattern(pattern int, state *State) error {
+	return s.patternEdit(pattern, state, s.seq.PastePattern)
+}
+
+// ClearPattern clears the given pattern.
+func (s *Service) ClearPattern(pattern int, state *State) error {
+	return s.patternEdit(pattern, state, s.seq.ClearPattern)
+}
+
+// RotateTrack shifts the steps of the given track.
+func (s *Service) RotateTrack(args RotateArgs, state *State) error {
+	return s.trackEdit(args.Track, state, func(track int) {
+		s.seq.RotateTrack(track, args.Steps)
+	})
+}
+
+// ReverseTrack reverses the order of the steps of the given track.
+func (s *Service) ReverseTrack(track int, state *State) error {
+	return s.trackEdit(track, state, s.seq.ReverseTrack)
+}
+
+// InvertTrack activates the inactive steps of the given track and
+// deactivates the active ones.
+func (s *Service) InvertTrack(track int, state *State) error {
+	return s.trackEdit(track, state, s.seq.InvertTrack)
+}
+
+// DoubleTrack duplicates the steps of the given track at its end.
+func (s *Service) DoubleTrack(track int, state *State) error {
+	return s.trackEdit(track, state, s.seq.DoubleTrack)
+}
+
+// HalveTrack removes the second half of the steps of the given track.
+func (s *Service) HalveTrack(track int, state *State) error {
+	return s.trackEdit(track, state, s.seq.HalveTrack)
+}
+
+// Randomize generates the steps of the given track.
+func (s *Service) Randomize(args RandomizeArgs, state *State) error {
+	return s.trackEdit(args.Track, state, func(track int) {
+		s.seq.Randomize(track, args.Settings)
+	})
+}
+
+// Undo reverts the last edit.
+func (s *Service) Undo(_ struct{}, state *HistoryState) error {
+	return s.apply(&state.State, func() error {
+		state.Edit = s.seq.Undo()
+		return nil
+	})
+}
+
+// Redo applies the last reverted edit again.
+func (s *Service) Redo(_ struct{}, state *HistoryState) error {
+	return s.apply(&state.State, func() error {
+		state.Edit = s.seq.Redo()
+		return nil
+	})
+}
+
+func (s *Service) patternEdit(pattern int, state *State, edit func(pattern int)) error {
+	return s.apply(state, func() error {
+		if err := s.checkPattern(pattern); err != nil {
+			return err
+		}
+		edit(pattern)
+		return nil
+	})
+}
+
+func (s *Service) trackEdit(track int, state *State, edit func(track int)) error {
+	return s.apply(state, func() error {
+		if err := s.checkTrack(track); err != nil {
+			return err
+		}
+		edit(track)
+		return nil
+	})
+}
+
+func (s *Service) stepEdit(args StepArgs, state *State, edit func(track, step int)) error {
+	return s.apply(state, func() error {
+		if err := s.checkStep(args.Track, args.Step); err != nil {
+			return err
+		}
+		edit(args.Track, args.Step)
+		return nil
+	})
+}
+
+func (s *Service) rangeEdit(args RangeArgs, state *State, edit func(track, first, last int)) error {
+	return s.apply(state, func() error {
+		if err := s.checkStep(args.Track, args.First); err != nil {
+			return err
+		}
+		if err := s.checkStep(args.Track, args.Last); err != nil {
+			return err
+		}
+		if args.First > args.Last {
+			return fmt.Errorf("invalid steps %d-%d", args.First, args.Last)
+		}
+		edit(args.Track, args.First, args.Last)
+		return nil
+	})
+}
+
+func (s *Service) controlEdit(args ControlArgs, state *State, edit func(track sequencer.Track)) error {
+	return s.apply(state, func() error {
+		if err := s.checkTrack(args.Track); err != nil {
+			return err
+		}
+		track := s.seq.Tracks()[args.Track]
+		if err := checkControl(len(track.Controls()), args.Control); err != nil {
+			return err
+		}
+		edit(track)
+		return nil
+	})
+}
+
+// check returns an error if one of the parameters is out of range, or if a
+// control isn't active on the given track, before any of them is set.
+func (p Parameters) check(track sequencer.Track) error {
+	for _, note := range p.Chord {
+		if note > 127 {
+			return fmt.Errorf("invalid note %d", note)
+		}
+	}
+	if p.Velocity != nil && (*p.Velocity < 0 || *p.Velocity > 127) {
+		return fmt.Errorf("invalid velocity %d", *p.Velocity)
+	}
+	for control := range p.Controls {
+		if !track.IsActiveControl(control) {
+			return fmt.Errorf("control %d isn't active on the track (check AddControl)", control)
+		}
+	}
+	return nil
+}
+
+// set sets the parameters on the given track or step. Like in the ui, the
+// values out of range of the length and probability are ignored.
+func (p Parameters) set(item sequencer.Parametrable) {
+	if len(p.Chord) > 0 {
+		item.SetChord(p.Chord)
+	}
+	if p.Length != nil {
+		item.SetLength(*p.Length)
+	}
+	if p.Velocity != nil {
+		item.SetVelocity(uint8(*p.Velocity))
+	}
+	if p.Probability != nil {
+		item.SetProbability(*p.Probability)
+	}
+	for control, value := range p.Controls {
+		item.SetControl(control, value)
+	}
+}
+
+// checkControl returns an error if the given control number isn't one of
+// the given number of controls of a track.
+func checkControl(controls, control int) error {
+	if control < 0 || control >= controls {
+		return fmt.Errorf("invalid control %d", control)
+	}
+	return nil
+}
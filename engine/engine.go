@@ -0,0 +1,126 @@
+// Package engine allows to control a running sequencer through a local Unix
+// domain socket, and to attach terminal uis to it without stopping playback.
+//
+// Clients start by sending a single command line:
+//   - "rpc" to send JSON-RPC 1.0 requests (check the Service methods),
+//   - "attach" to run a terminal ui session in the engine process, sharing
+//     its sequencer with the other sessions. The client then sends its
+//     terminal input and size in frames (check frame.go) and receives the ui
+//     output as is.
+package engine
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/rpc"
+	"net/rpc/jsonrpc"
+	"os"
+	"strings"
+
+	"sektron/filesystem"
+	"sektron/sequencer"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+)
+
+const (
+	rpcCommand    = "rpc"
+	attachCommand = "attach"
+
+	// serviceName is the prefix of the JSON-RPC methods, e.g.
+	// "Sektron.TogglePlay".
+	serviceName = "Sektron"
+)
+
+// Server serves the sequencer on a Unix domain socket.
+type Server struct {
+	seq      sequencer.Sequencer
+	config   filesystem.Configuration // shared by the ui sessions
+	listener net.Listener
+	rpc      *rpc.Server
+}
+
+// Listen creates the socket at the given path and starts serving the
+// sequencer. It fails if another engine is already listening on it.
+func Listen(path string, config filesystem.Configuration, seq sequencer.Sequencer) (*Server, error) {
+	if conn, err := net.Dial("unix", path); err == nil {
+		conn.Close()
+		return nil, fmt.Errorf("an engine is already listening on %s", path)
+	}
+	// The socket may remain from an engine that didn't exit properly.
+	os.Remove(path)
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+
+	server := rpc.NewServer()
+	if err := server.RegisterName(serviceName, &Service{seq: seq}); err != nil {
+		listener.Close()
+		return nil, err
+	}
+
+	// The attached terminals can't be queried for their capabilities, so we
+	// assume they're as capable as the ones the ui is usually run in.
+	lipgloss.SetColorProfile(termenv.ANSI256)
+	lipgloss.SetHasDarkBackground(true)
+
+	s := &Server{
+		seq:      seq,
+		config:   config,
+		listener: listener,
+		rpc:      server,
+	}
+	go s.serve()
+	return s, nil
+}
+
+// Close stops accepting connections and removes the socket.
+func (s *Server) Close() error {
+	return s.listener.Close()
+}
+
+func (s *Server) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *Server) handle(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	command, err := r.ReadString('\n')
+	if err != nil {
+		return
+	}
+	switch strings.TrimSpace(command) {
+	case rpcCommand:
+		s.rpc.ServeCodec(jsonrpc.NewServerCodec(bufferedConn{Reader: r, Conn: conn}))
+	case attachCommand:
+		if err := s.attach(r, conn); err != nil {
+			log.Printf("ui session: %s", err)
+		}
+	default:
+		fmt.Fprintf(conn, "unknown command %q\n", strings.TrimSpace(command))
+	}
+}
+
+// bufferedConn reads from the buffered reader used to read the command line,
+// which may already contain the first requests.
+type bufferedConn struct {
+	io.Reader
+	net.Conn
+}
+
+func (c bufferedConn) Read(p []byte) (int, error) {
+	return c.Reader.Read(p)
+}
@@ -0,0 +1,58 @@
+package engine
+
+import (
+	"encoding/binary"
+	"io"
+	"sync"
+)
+
+// Attached clients send their terminal input and size in frames: a type
+// byte, the payload length as a big endian uint32, then the payload.
+const (
+	// frameInput holds raw terminal input.
+	frameInput byte = iota
+	// frameResize holds the terminal width and height as big endian uint16.
+	frameResize
+
+	maxFrameSize = 64 * 1024
+)
+
+type frameWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (f *frameWriter) write(kind byte, payload []byte) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	header := make([]byte, 5)
+	header[0] = kind
+	binary.BigEndian.PutUint32(header[1:], uint32(len(payload)))
+	if _, err := f.w.Write(append(header, payload...)); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (f *frameWriter) resize(width, height int) error {
+	payload := make([]byte, 4)
+	binary.BigEndian.PutUint16(payload, uint16(width))
+	binary.BigEndian.PutUint16(payload[2:], uint16(height))
+	return f.write(frameResize, payload)
+}
+
+func readFrame(r io.Reader) (byte, []byte, error) {
+	header := make([]byte, 5)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return 0, nil, err
+	}
+	size := binary.BigEndian.Uint32(header[1:])
+	if size > maxFrameSize {
+		return 0, nil, io.ErrShortBuffer
+	}
+	payload := make([]byte, size)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return 0, nil, err
+	}
+	return header[0], payload, nil
+}
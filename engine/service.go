@@ -0,0 +1,271 @@
+package engine
+
+import (
+	"fmt"
+
+	"sektron/filesystem"
+	"sektron/sequencer"
+)
+
+// Service exposes the Sequencer interface through JSON-RPC. Patterns, tracks
+// and steps are numbered from 0, as in the Sequencer interface. The methods
+// that change the sequencer reply with its state once the request has been
+// applied. The requests are served concurrently, each of them holds the
+// sequencer lock.
+//
+// Lock and Unlock aren't exposed, as each request holds the lock, nor are
+// Edit and OnTrigger, which take funcs: each edit request is recorded in the
+// undo history on its own, and the triggered steps are streamed by the api
+// (check the api package).
+type Service struct {
+	seq sequencer.Sequencer
+}
+
+// State represents the sequencer state returned to the clients.
+type State struct {
+	Playing bool    `json:"playing"`
+	Tempo   float64 `json:"tempo"`
+	Pattern int     `json:"pattern"`
+	Chain   []int   `json:"chain"`
+
+	// Tracks holds the current step of each track, or -1 if the track is
+	// muted.
+	Tracks []int `json:"tracks"`
+}
+
+// StepArgs represents a step of a track.
+type StepArgs struct {
+	Track int `json:"track"`
+	Step  int `json:"step"`
+}
+
+// PatternArgs represents the content of a bank slot.
+type PatternArgs struct {
+	Pattern int                `json:"pattern"`
+	Content filesystem.Pattern `json:"content"`
+}
+
+// ExportArgs represents an export to a Standard MIDI File (check
+// Sequencer.Export). The file is written by the engine.
+type ExportArgs struct {
+	Filename string `json:"filename"`
+	Patterns []int  `json:"patterns"`
+	Seed     int64  `json:"seed"`
+}
+
+// ImportArgs represents the import of a Standard MIDI File to a pattern
+// (check Sequencer.Import). The file is read by the engine.
+type ImportArgs struct {
+	Filename string `json:"filename"`
+	Pattern  int    `json:"pattern"`
+}
+
+// apply calls the given func while holding the sequencer lock, then replies
+// with the sequencer state.
+func (s *Service) apply(state *State, f func() error) error {
+	s.seq.Lock()
+	defer s.seq.Unlock()
+	if err := f(); err != nil {
+		return err
+	}
+	*state = CurrentState(s.seq)
+	return nil
+}
+
+// State returns the sequencer state.
+func (s *Service) State(_ struct{}, state *State) error {
+	return s.apply(state, func() error { return nil })
+}
+
+// TogglePlay starts or stops playing.
+func (s *Service) TogglePlay(_ struct{}, state *State) error {
+	return s.apply(state, func() error {
+		s.seq.TogglePlay()
+		return nil
+	})
+}
+
+// Reset stops the playing notes and moves the playhead to the beginning.
+func (s *Service) Reset(_ struct{}, state *State) error {
+	return s.apply(state, func() error {
+		s.seq.Reset()
+		return nil
+	})
+}
+
+// SetTempo sets the tempo in bpm.
+func (s *Service) SetTempo(tempo float64, state *State) error {
+	return s.apply(state, func() error {
+		s.seq.SetTempo(tempo)
+		return nil
+	})
+}
+
+// TapTempo registers a tap, the tempo being set from the last taps.
+func (s *Service) TapTempo(_ struct{}, state *State) error {
+	return s.apply(state, func() error {
+		s.seq.TapTempo()
+		return nil
+	})
+}
+
+// Nudge temporarily speeds up (positive direction) or slows down (negative
+// direction) the clock.
+func (s *Service) Nudge(direction int, state *State) error {
+	return s.apply(state, func() error {
+		s.seq.Nudge(direction)
+		return nil
+	})
+}
+
+// SetTempoRamp sets the active pattern tempo ramp, or removes it if it has
+// no bars.
+func (s *Service) SetTempoRamp(ramp filesystem.TempoRamp, state *State) error {
+	return s.apply(state, func() error {
+		s.seq.SetTempoRamp(ramp)
+		return nil
+	})
+}
+
+// Save saves the active pattern to the bank.
+func (s *Service) Save(_ struct{}, state *State) error {
+	return s.apply(state, func() error {
+		s.seq.Save()
+		return nil
+	})
+}
+
+// Load switches to the given pattern: right away when stopped, at the end of
+// the current one when playing, as in the ui.
+func (s *Service) Load(pattern int, state *State) error {
+	return s.apply(state, func() error {
+		if err := s.checkPattern(pattern); err != nil {
+			return err
+		}
+		if s.seq.IsPlaying() {
+			s.seq.ChainNow(pattern)
+		} else {
+			s.seq.Save()
+			s.seq.Load(pattern)
+		}
+		return nil
+	})
+}
+
+// LoadNextInChain switches to the next pattern of the chain.
+func (s *Service) LoadNextInChain(_ struct{}, state *State) error {
+	return s.apply(state, func() error {
+		s.seq.LoadNextInChain()
+		return nil
+	})
+}
+
+// Chain adds the given pattern to the chain.
+func (s *Service) Chain(pattern int, state *State) error {
+	return s.apply(state, func() error {
+		if err := s.checkPattern(pattern); err != nil {
+			return err
+		}
+		s.seq.Chain(pattern)
+		return nil
+	})
+}
+
+// ChainNow replaces the chain with the given pattern, played at the end of
+// the current one.
+func (s *Service) ChainNow(pattern int, state *State) error {
+	return s.apply(state, func() error {
+		if err := s.checkPattern(pattern); err != nil {
+			return err
+		}
+		s.seq.ChainNow(pattern)
+		return nil
+	})
+}
+
+// Patterns returns the bank patterns.
+func (s *Service) Patterns(_ struct{}, patterns *[]filesystem.Pattern) error {
+	s.seq.Lock()
+	defer s.seq.Unlock()
+	*patterns = s.seq.Patterns()
+	return nil
+}
+
+// Pattern returns the given pattern. The active pattern holds the current
+// state of the tracks.
+func (s *Service) Pattern(pattern int, p *filesystem.Pattern) error {
+	s.seq.Lock()
+	defer s.seq.Unlock()
+	if err := s.checkPattern(pattern); err != nil {
+		return err
+	}
+	*p = s.seq.Pattern(pattern)
+	return nil
+}
+
+// SetPattern replaces the given pattern.
+func (s *Service) SetPattern(args PatternArgs, state *State) error {
+	return s.apply(state, func() error {
+		return s.seq.SetPattern(args.Pattern, args.Content)
+	})
+}
+
+// Export renders the given patterns to a Standard MIDI File.
+func (s *Service) Export(args ExportArgs, state *State) error {
+	return s.apply(state, func() error {
+		return s.seq.Export(args.Filename, args.Patterns, args.Seed)
+	})
+}
+
+// Import reads a Standard MIDI File into the given pattern.
+func (s *Service) Import(args ImportArgs, state *State) error {
+	return s.apply(state, func() error {
+		if err := s.checkPattern(args.Pattern); err != nil {
+			return err
+		}
+		return s.seq.Import(args.Filename, args.Pattern)
+	})
+}
+
+func (s *Service) checkPattern(pattern int) error {
+	if pattern < 0 || pattern >= len(s.seq.Patterns()) {
+		return fmt.Errorf("invalid pattern %d", pattern)
+	}
+	return nil
+}
+
+func (s *Service) checkTrack(track int) error {
+	if track < 0 || track >= len(s.seq.Tracks()) {
+		return fmt.Errorf("invalid track %d", track)
+	}
+	return nil
+}
+
+func (s *Service) checkStep(track, step int) error {
+	if err := s.checkTrack(track); err != nil {
+		return err
+	}
+	if step < 0 || step >= len(s.seq.Tracks()[track].Steps()) {
+		return fmt.Errorf("invalid step %d", step)
+	}
+	return nil
+}
+
+// CurrentState returns the state of the given sequencer. The sequencer lock
+// must be held.
+func CurrentState(seq sequencer.Sequencer) State {
+	state := State{
+		Playing: seq.IsPlaying(),
+		Tempo:   seq.Tempo(),
+		Pattern: seq.ActivePattern(),
+		Chain:   seq.FullChain(),
+	}
+	for _, track := range seq.Tracks() {
+		step := -1
+		if track.IsActive() {
+			step = track.CurrentStep()
+		}
+		state.Tracks = append(state.Tracks, step)
+	}
+	return state
+}
@@ -0,0 +1,128 @@
+package engine
+
+import (
+	"sektron/filesystem"
+	"sektron/sequencer"
+)
+
+// ClockState represents the clock state returned to the clients. The values
+// that don't apply are omitted, e.g. the external tempo when not synced to
+// an external clock.
+type ClockState struct {
+	Tempo         float64               `json:"tempo"`
+	Ramp          *filesystem.TempoRamp `json:"ramp,omitempty"`
+	RampingTo     *float64              `json:"ramping_to,omitempty"`
+	CountIn       *int                  `json:"count_in,omitempty"`
+	ExternalTempo *float64              `json:"external_tempo,omitempty"`
+	LinkPeers     *int                  `json:"link_peers,omitempty"`
+	Stats         sequencer.ClockStats  `json:"stats"`
+}
+
+// Clock returns the clock state.
+func (s *Service) Clock(_ struct{}, clock *ClockState) error {
+	s.seq.Lock()
+	defer s.seq.Unlock()
+	*clock = ClockState{
+		Tempo: s.seq.Tempo(),
+		Stats: s.seq.ClockStats(),
+	}
+	if ramp, ok := s.seq.TempoRamp(); ok {
+		clock.Ramp = &ramp
+	}
+	if target, ok := s.seq.IsRamping(); ok {
+		clock.RampingTo = &target
+	}
+	if beats, ok := s.seq.CountIn(); ok {
+		clock.CountIn = &beats
+	}
+	if tempo, ok := s.seq.ExternalTempo(); ok {
+		clock.ExternalTempo = &tempo
+	}
+	if peers, ok := s.seq.LinkPeers(); ok {
+		clock.LinkPeers = &peers
+	}
+	return nil
+}
+
+// OutputDevices returns the names of the devices the tracks can send to.
+func (s *Service) OutputDevices(_ struct{}, devices *[]string) error {
+	s.seq.Lock()
+	defer s.seq.Unlock()
+	*devices = s.seq.OutputDevices()
+	return nil
+}
+
+// The following methods change the running sequencer settings. Unlike the
+// ui settings, they don't change the configuration file.
+
+// SetClockOutputs sets the devices the clock is sent to.
+func (s *Service) SetClockOutputs(outputs []filesystem.ClockOutput, state *State) error {
+	return s.apply(state, func() error {
+		s.seq.SetClockOutputs(outputs)
+		return nil
+	})
+}
+
+// SyncTo follows the clock of the given midi input, or the internal clock
+// if negative.
+func (s *Service) SyncTo(input int, state *State) error {
+	return s.apply(state, func() error {
+		return s.seq.SyncTo(input)
+	})
+}
+
+// EnableLink joins an Ableton Link session.
+func (s *Service) EnableLink(_ struct{}, state *State) error {
+	return s.apply(state, s.seq.EnableLink)
+}
+
+// DisableLink leaves the Ableton Link session.
+func (s *Service) DisableLink(_ struct{}, state *State) error {
+	return s.apply(state, func() error {
+		s.seq.DisableLink()
+		return nil
+	})
+}
+
+// EnableThru forwards the messages of the given midi inputs.
+func (s *Service) EnableThru(settings filesystem.Thru, state *State) error {
+	return s.apply(state, func() error {
+		return s.seq.EnableThru(settings)
+	})
+}
+
+// DisableThru stops forwarding the midi inputs messages.
+func (s *Service) DisableThru(_ struct{}, state *State) error {
+	return s.apply(state, func() error {
+		s.seq.DisableThru()
+		return nil
+	})
+}
+
+// SetThruTrack sets the track that receives the midi thru messages.
+func (s *Service) SetThruTrack(track int, state *State) error {
+	return s.trackEdit(track, state, s.seq.SetThruTrack)
+}
+
+// EnableRemote responds to the remote control messages of the given input.
+func (s *Service) EnableRemote(settings filesystem.Remote, state *State) error {
+	return s.apply(state, func() error {
+		return s.seq.EnableRemote(settings)
+	})
+}
+
+// DisableRemote stops responding to the remote control messages.
+func (s *Service) DisableRemote(_ struct{}, state *State) error {
+	return s.apply(state, func() error {
+		s.seq.DisableRemote()
+		return nil
+	})
+}
+
+// SetMetronome sets the metronome settings.
+func (s *Service) SetMetronome(settings filesystem.Metronome, state *State) error {
+	return s.apply(state, func() error {
+		s.seq.SetMetronome(settings)
+		return nil
+	})
+}
@@ -0,0 +1,12 @@
+package filesystem
+
+// API represents the HTTP api settings.
+// Address holds the address to listen on, e.g. ":8080" to allow phones on the
+// local network. The api is disabled when empty.
+// Origins holds the origins of the browser pages allowed to use the api, e.g.
+// "http://localhost:3000", or "null" for the pages opened from the
+// filesystem.
+type API struct {
+	Address string   `json:"address"`
+	Origins []string `json:"origins"`
+}
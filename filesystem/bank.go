@@ -23,8 +23,16 @@ type Bank struct {
 
 // Pattern represents a sequencer state that is json serializable.
 type Pattern struct {
-	Tracks []Track `json:"tracks"`
-	Tempo  float64 `json:"tempo"`
+	Tracks []Track    `json:"tracks"`
+	Tempo  float64    `json:"tempo"`
+	Ramp   *TempoRamp `json:"ramp,omitempty"`
+}
+
+// TempoRamp represents a tempo automation that makes the pattern go from its
+// tempo to the target tempo over the given number of bars.
+type TempoRamp struct {
+	Target float64 `json:"target"`
+	Bars   int     `json:"bars"`
 }
 
 // IsFree returns true if the pattern is not used, false otherwise.
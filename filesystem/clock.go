@@ -0,0 +1,41 @@
+package filesystem
+
+// Clock represents the clock settings.
+// Input holds the name of the midi input port to sync to. The internal
+// clock is used when empty.
+// Link enables the Ableton Link session.
+// Outputs holds the clock settings of each midi output device.
+type Clock struct {
+	Input   string        `json:"input"`
+	Link    bool          `json:"link"`
+	Outputs []ClockOutput `json:"outputs"`
+}
+
+// ClockOutput holds the clock settings of a midi output device, identified
+// by its name. Clock enables the Timing Clock messages and Transport the
+// Start, Stop, Continue and Song Position Pointer messages.
+// The clock rate sent to the device is multiplied by Multiplier and divided
+// by Divider. Offset delays the clock and transport messages by the given
+// number of milliseconds, to compensate the latency of the other devices.
+// TimeCode holds the frame rate of the MIDI Time Code sent to the device:
+// "24", "25", "29.97" (drop frame) or "30". It's disabled when empty.
+type ClockOutput struct {
+	Device     string `json:"device"`
+	Clock      bool   `json:"clock"`
+	Transport  bool   `json:"transport"`
+	Divider    int    `json:"divider"`
+	Multiplier int    `json:"multiplier"`
+	Offset     int    `json:"offset"`
+	TimeCode   string `json:"timecode"`
+}
+
+// NewClockOutput returns the default clock settings for the given device.
+func NewClockOutput(device string, enabled bool) ClockOutput {
+	return ClockOutput{
+		Device:     device,
+		Clock:      enabled,
+		Transport:  enabled,
+		Divider:    1,
+		Multiplier: 1,
+	}
+}
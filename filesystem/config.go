@@ -10,15 +10,32 @@ import (
 
 // Configuration represents a configuration loaded from a json file.
 type Configuration struct {
-	KeyMap   KeyMap `json:"keymap"`
+	KeyMap     KeyMap     `json:"keymap"`
+	Randomizer Randomizer `json:"randomizer"`
+	Clock      Clock      `json:"clock"`
+	Metronome  Metronome  `json:"metronome"`
+	OSC        OSC        `json:"osc"`
+	API        API        `json:"api"`
+	Grid       Grid       `json:"grid"`
+	Thru       Thru       `json:"thru"`
+	Remote     Remote     `json:"remote"`
+	Export     Export     `json:"export"`
+
+	// Controllers holds the midi learn mappings of each midi controller, by
+	// input port name.
+	Controllers map[string][]MidiMapping `json:"controllers"`
+
 	filename string
 }
 
 // NewConfiguration returns a new default configuration.
 func NewConfiguration(filename, keyboard string) Configuration {
 	config := Configuration{
-		KeyMap:   NewDefaultQwertyKeyMap(),
-		filename: filename,
+		KeyMap:     NewDefaultQwertyKeyMap(),
+		Randomizer: NewDefaultRandomizer(),
+		Metronome:  NewDefaultMetronome(),
+		Remote:     NewDefaultRemote(),
+		filename:   filename,
 	}
 	config.Load(filename)
 
@@ -50,6 +67,12 @@ func (c *Configuration) Save() {
 	}
 }
 
+// Reload reads the configuration file again, e.g. to get the changes saved
+// by another ui.
+func (c *Configuration) Reload() {
+	*c = NewConfiguration(c.filename, "")
+}
+
 // Load reads a json and unmarshal its content to the Configuration.
 func (c *Configuration) Load(filename string) {
 	f, err := os.Open(filename)
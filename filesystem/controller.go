@@ -0,0 +1,20 @@
+package filesystem
+
+// MidiMapping binds a control change or a note of a midi controller to a ui
+// action or to a track or step parameter.
+// Message is either "cc" or "note", received on Channel (0 to 15) with the
+// given controller or note Number.
+// Action holds the name of a keymap entry (e.g. "tempo_up", "steps_toggle.3"
+// for the 3rd key of an entry with multiple keys, or "play").
+// Param holds the name of a parameter (e.g. "velocity" or "control 74"). Its
+// Mode is either "absolute" (default): the control value is scaled to the
+// parameter range, or "relative" for endless encoders sending 1 to 63 to
+// increase and 65 to 127 to decrease the value.
+type MidiMapping struct {
+	Message string `json:"message"`
+	Channel uint8  `json:"channel"`
+	Number  uint8  `json:"number"`
+	Action  string `json:"action,omitempty"`
+	Param   string `json:"param,omitempty"`
+	Mode    string `json:"mode,omitempty"`
+}
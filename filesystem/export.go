@@ -0,0 +1,11 @@
+package filesystem
+
+// Export represents the Standard MIDI File export settings.
+// Directory holds where the ui writes the exported files, the current
+// directory when empty.
+// Seed holds the seed the step probabilities are drawn from, so that
+// exporting the same patterns twice gives the same file.
+type Export struct {
+	Directory string `json:"directory"`
+	Seed      int64  `json:"seed"`
+}
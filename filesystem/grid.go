@@ -0,0 +1,94 @@
+package filesystem
+
+// Grid represents the grid controller settings.
+// Input and Output hold the midi port names of the controller (Output
+// defaults to Input). The grid is disabled when Input is empty.
+// Profile holds the name of the profile describing the controller messages,
+// among Profiles and the default ones ("launchpad-x" when empty).
+type Grid struct {
+	Input    string        `json:"input"`
+	Output   string        `json:"output"`
+	Profile  string        `json:"profile"`
+	Profiles []GridProfile `json:"profiles"`
+}
+
+// GridProfile describes the midi messages of an 8x8 grid controller.
+// Pads send and receive notes on Channel: the top left pad note is Origin,
+// and each row down adds RowStep (e.g. -10 for the Launchpad programmer
+// layout, where the bottom left pad is 11).
+// Buttons holds the extra buttons messages, by action: "play", "page_up"
+// and "page_down".
+// Colors holds the LED velocities, by state: "off", "step", "playhead",
+// "track", "selected", "muted", "pattern", "active_pattern" and "play".
+// Init holds a sysex message sent when the grid starts (e.g. to switch to
+// the programmer mode), as hexadecimal bytes without F0 and F7.
+type GridProfile struct {
+	Name    string                `json:"name"`
+	Channel uint8                 `json:"channel"`
+	Origin  int                   `json:"origin"`
+	RowStep int                   `json:"row_step"`
+	Buttons map[string]GridButton `json:"buttons"`
+	Colors  map[string]uint8      `json:"colors"`
+	Init    string                `json:"init,omitempty"`
+}
+
+// GridButton represents a button message: "cc" or "note", and its number.
+type GridButton struct {
+	Message string `json:"message"`
+	Number  uint8  `json:"number"`
+}
+
+// NewDefaultGridProfiles returns the profiles of the supported grid
+// controllers.
+func NewDefaultGridProfiles() []GridProfile {
+	return []GridProfile{
+		{
+			// Programmer mode, with the default color palette.
+			Name:    "launchpad-x",
+			Channel: 0,
+			Origin:  81,
+			RowStep: -10,
+			Buttons: map[string]GridButton{
+				"play":      {Message: "cc", Number: 89},
+				"page_up":   {Message: "cc", Number: 91},
+				"page_down": {Message: "cc", Number: 92},
+			},
+			Colors: map[string]uint8{
+				"off":            0,
+				"step":           45,
+				"playhead":       3,
+				"track":          1,
+				"selected":       13,
+				"muted":          5,
+				"pattern":        1,
+				"active_pattern": 21,
+				"play":           21,
+			},
+			Init: "00 20 29 02 0C 0E 01",
+		},
+		{
+			// Launchpad Mini and S: the velocity holds the red and green
+			// brightness, plus the flags to clear and copy the buffers.
+			Name:    "launchpad-mini",
+			Channel: 0,
+			Origin:  0,
+			RowStep: 16,
+			Buttons: map[string]GridButton{
+				"play":      {Message: "note", Number: 8},
+				"page_up":   {Message: "cc", Number: 104},
+				"page_down": {Message: "cc", Number: 105},
+			},
+			Colors: map[string]uint8{
+				"off":            12,
+				"step":           60,
+				"playhead":       63,
+				"track":          28,
+				"selected":       62,
+				"muted":          15,
+				"pattern":        29,
+				"active_pattern": 62,
+				"play":           60,
+			},
+		},
+	}
+}
@@ -2,64 +2,118 @@ package filesystem
 
 // KeyMap represents a keyboard mapping loaded from a json file.
 type KeyMap struct {
-	Tracks       [10]string `json:"tracks"`
-	TracksToggle [10]string `json:"tracks_toggle"`
-	Steps        [16]string `json:"steps"`
-	StepsToggle  [16]string `json:"steps_toggle"`
-	ParamMode    string     `json:"param_mode"`
-	PatternMode  string     `json:"pattern_mode"`
-	AddTrack     string     `json:"add_track"`
-	RemoveTrack  string     `json:"remove_track"`
-	AddStep      string     `json:"add_step"`
-	RemoveStep   string     `json:"remove_step"`
-	CopyStep     string     `json:"copy_step"`
-	PasteStep    string     `json:"paste_step"`
-	PreviousStep string     `json:"previous_step"`
-	NextStep     string     `json:"next_step"`
-	PageUp       string     `json:"page_up"`
-	PageDown     string     `json:"page_down"`
-	TempoUp      string     `json:"tempo_up"`
-	TempoDown    string     `json:"tempo_down"`
-	AddParam     string     `json:"add_param"`
-	RemoveParam  string     `json:"remove_param"`
-	Validate     string     `json:"validate"`
-	Left         string     `json:"left"`
-	Right        string     `json:"right"`
-	Up           string     `json:"up"`
-	Down         string     `json:"down"`
-	Help         string     `json:"help"`
-	Quit         string     `json:"quit"`
+	Tracks        [10]string `json:"tracks"`
+	TracksToggle  [10]string `json:"tracks_toggle"`
+	Steps         [16]string `json:"steps"`
+	StepsToggle   [16]string `json:"steps_toggle"`
+	ParamMode     string     `json:"param_mode"`
+	PatternMode   string     `json:"pattern_mode"`
+	AddTrack      string     `json:"add_track"`
+	RemoveTrack   string     `json:"remove_track"`
+	AddStep       string     `json:"add_step"`
+	RemoveStep    string     `json:"remove_step"`
+	CopyStep      string     `json:"copy_step"`
+	PasteStep     string     `json:"paste_step"`
+	ClearStep     string     `json:"clear_step"`
+	CopyTrack     string     `json:"copy_track"`
+	PasteTrack    string     `json:"paste_track"`
+	ClearTrack    string     `json:"clear_track"`
+	RotateLeft    string     `json:"rotate_left"`
+	RotateRight   string     `json:"rotate_right"`
+	Reverse       string     `json:"reverse"`
+	Invert        string     `json:"invert"`
+	Double        string     `json:"double"`
+	Halve         string     `json:"halve"`
+	Randomize     string     `json:"randomize"`
+	SelectRange   string     `json:"select_range"`
+	SelectEvery   string     `json:"select_every"`
+	SelectActive  string     `json:"select_active"`
+	EditMode      string     `json:"edit_mode"`
+	Undo          string     `json:"undo"`
+	Redo          string     `json:"redo"`
+	PreviousStep  string     `json:"previous_step"`
+	NextStep      string     `json:"next_step"`
+	PageUp        string     `json:"page_up"`
+	PageDown      string     `json:"page_down"`
+	TempoUp       string     `json:"tempo_up"`
+	TempoDown     string     `json:"tempo_down"`
+	FineTempoUp   string     `json:"fine_tempo_up"`
+	FineTempoDown string     `json:"fine_tempo_down"`
+	TapTempo      string     `json:"tap_tempo"`
+	NudgeUp       string     `json:"nudge_up"`
+	NudgeDown     string     `json:"nudge_down"`
+	TempoEntry    string     `json:"tempo_entry"`
+	ClockStats    string     `json:"clock_stats"`
+	Settings      string     `json:"settings"`
+	MidiLearn     string     `json:"midi_learn"`
+	Export        string     `json:"export"`
+	AddParam      string     `json:"add_param"`
+	RemoveParam   string     `json:"remove_param"`
+	Validate      string     `json:"validate"`
+	Left          string     `json:"left"`
+	Right         string     `json:"right"`
+	Up            string     `json:"up"`
+	Down          string     `json:"down"`
+	Help          string     `json:"help"`
+	Quit          string     `json:"quit"`
 }
 
 // NewDefaultAzertyKeyMap returns a new default KeyMap for azerty keyboards.
 func NewDefaultAzertyKeyMap() KeyMap {
 	return KeyMap{
-		Tracks:       [10]string{"&", "é", "\"", "'", "(", "-", "è", "_", "ç", "à"},
-		TracksToggle: [10]string{"1", "2", "3", "4", "5", "6", "7", "8", "9", "0"},
-		Steps:        [16]string{"a", "z", "e", "r", "t", "y", "u", "i", "q", "s", "d", "f", "g", "h", "j", "k"},
-		StepsToggle:  [16]string{"A", "Z", "E", "R", "T", "Y", "U", "I", "Q", "S", "D", "F", "G", "H", "J", "K"},
-		ParamMode:    "tab",
-		PatternMode:  "²",
-		AddTrack:     "=",
-		RemoveTrack:  ")",
-		AddStep:      "+",
-		RemoveStep:   "°",
-		CopyStep:     "ctrl+c",
-		PasteStep:    "ctrl+v",
-		PreviousStep: ",",
-		NextStep:     ";",
-		PageUp:       "p",
-		PageDown:     "m",
-		TempoUp:      "shift+up",
-		TempoDown:    "shift+down",
-		AddParam:     "ctrl+up",
-		RemoveParam:  "ctrl+down",
-		Validate:     "enter",
-		Left:         "left",
-		Right:        "right",
-		Up:           "up",
-		Down:         "down",
-		Help:         "?",
+		Tracks:        [10]string{"&", "é", "\"", "'", "(", "-", "è", "_", "ç", "à"},
+		TracksToggle:  [10]string{"1", "2", "3", "4", "5", "6", "7", "8", "9", "0"},
+		Steps:         [16]string{"a", "z", "e", "r", "t", "y", "u", "i", "q", "s", "d", "f", "g", "h", "j", "k"},
+		StepsToggle:   [16]string{"A", "Z", "E", "R", "T", "Y", "U", "I", "Q", "S", "D", "F", "G", "H", "J", "K"},
+		ParamMode:     "tab",
+		PatternMode:   "²",
+		AddTrack:      "=",
+		RemoveTrack:   ")",
+		AddStep:       "+",
+		RemoveStep:    "°",
+		CopyStep:      "ctrl+c",
+		PasteStep:     "ctrl+v",
+		ClearStep:     "ctrl+x",
+		CopyTrack:     "alt+c",
+		PasteTrack:    "alt+v",
+		ClearTrack:    "alt+x",
+		RotateLeft:    "alt+left",
+		RotateRight:   "alt+right",
+		Reverse:       "alt+r",
+		Invert:        "alt+i",
+		Double:        "alt+d",
+		Halve:         "alt+h",
+		Randomize:     "ctrl+g",
+		SelectRange:   "ctrl+r",
+		SelectEvery:   "ctrl+n",
+		SelectActive:  "ctrl+a",
+		EditMode:      "ctrl+e",
+		Undo:          "ctrl+z",
+		Redo:          "ctrl+y",
+		PreviousStep:  ",",
+		NextStep:      ";",
+		PageUp:        "p",
+		PageDown:      "m",
+		TempoUp:       "shift+up",
+		TempoDown:     "shift+down",
+		FineTempoUp:   "alt+up",
+		FineTempoDown: "alt+down",
+		TapTempo:      "b",
+		NudgeUp:       "shift+right",
+		NudgeDown:     "shift+left",
+		TempoEntry:    "ctrl+t",
+		ClockStats:    "ctrl+k",
+		Settings:      "ctrl+o",
+		MidiLearn:     "ctrl+l",
+		Export:        "ctrl+w",
+		AddParam:      "ctrl+up",
+		RemoveParam:   "ctrl+down",
+		Validate:      "enter",
+		Left:          "left",
+		Right:         "right",
+		Up:            "up",
+		Down:          "down",
+		Help:          "?",
 	}
 }
 
@@ -67,64 +121,118 @@ func NewDefaultAzertyKeyMap() KeyMap {
 // keyboards.
 func NewDefaultAzertyMacKeyMap() KeyMap {
 	return KeyMap{
-		Tracks:       [10]string{"&", "é", "\"", "'", "(", "-", "è", "_", "ç", "à"},
-		TracksToggle: [10]string{"1", "2", "3", "4", "5", "6", "7", "8", "9", "0"},
-		Steps:        [16]string{"a", "z", "e", "r", "t", "y", "u", "i", "q", "s", "d", "f", "g", "h", "j", "k"},
-		StepsToggle:  [16]string{"A", "Z", "E", "R", "T", "Y", "U", "I", "Q", "S", "D", "F", "G", "H", "J", "K"},
-		ParamMode:    "tab",
-		PatternMode:  "@",
-		AddTrack:     "-",
-		RemoveTrack:  ")",
-		AddStep:      "_",
-		RemoveStep:   "°",
-		CopyStep:     "ctrl+c",
-		PasteStep:    "ctrl+v",
-		PreviousStep: ",",
-		NextStep:     ";",
-		PageUp:       "p",
-		PageDown:     "m",
-		TempoUp:      "shift+up",
-		TempoDown:    "shift+down",
-		AddParam:     "ctrl+up",
-		RemoveParam:  "ctrl+down",
-		Validate:     "enter",
-		Left:         "left",
-		Right:        "right",
-		Up:           "up",
-		Down:         "down",
-		Help:         "?",
+		Tracks:        [10]string{"&", "é", "\"", "'", "(", "-", "è", "_", "ç", "à"},
+		TracksToggle:  [10]string{"1", "2", "3", "4", "5", "6", "7", "8", "9", "0"},
+		Steps:         [16]string{"a", "z", "e", "r", "t", "y", "u", "i", "q", "s", "d", "f", "g", "h", "j", "k"},
+		StepsToggle:   [16]string{"A", "Z", "E", "R", "T", "Y", "U", "I", "Q", "S", "D", "F", "G", "H", "J", "K"},
+		ParamMode:     "tab",
+		PatternMode:   "@",
+		AddTrack:      "-",
+		RemoveTrack:   ")",
+		AddStep:       "_",
+		RemoveStep:    "°",
+		CopyStep:      "ctrl+c",
+		PasteStep:     "ctrl+v",
+		ClearStep:     "ctrl+x",
+		CopyTrack:     "alt+c",
+		PasteTrack:    "alt+v",
+		ClearTrack:    "alt+x",
+		RotateLeft:    "alt+left",
+		RotateRight:   "alt+right",
+		Reverse:       "alt+r",
+		Invert:        "alt+i",
+		Double:        "alt+d",
+		Halve:         "alt+h",
+		Randomize:     "ctrl+g",
+		SelectRange:   "ctrl+r",
+		SelectEvery:   "ctrl+n",
+		SelectActive:  "ctrl+a",
+		EditMode:      "ctrl+e",
+		Undo:          "ctrl+z",
+		Redo:          "ctrl+y",
+		PreviousStep:  ",",
+		NextStep:      ";",
+		PageUp:        "p",
+		PageDown:      "m",
+		TempoUp:       "shift+up",
+		TempoDown:     "shift+down",
+		FineTempoUp:   "alt+up",
+		FineTempoDown: "alt+down",
+		TapTempo:      "b",
+		NudgeUp:       "shift+right",
+		NudgeDown:     "shift+left",
+		TempoEntry:    "ctrl+t",
+		ClockStats:    "ctrl+k",
+		Settings:      "ctrl+o",
+		MidiLearn:     "ctrl+l",
+		Export:        "ctrl+w",
+		AddParam:      "ctrl+up",
+		RemoveParam:   "ctrl+down",
+		Validate:      "enter",
+		Left:          "left",
+		Right:         "right",
+		Up:            "up",
+		Down:          "down",
+		Help:          "?",
 	}
 }
 
 // NewDefaultQwertyKeyMap returns a new default KeyMap for qwerty keyboards.
 func NewDefaultQwertyKeyMap() KeyMap {
 	return KeyMap{
-		Tracks:       [10]string{"1", "2", "3", "4", "5", "6", "7", "8", "9", "0"},
-		TracksToggle: [10]string{"!", "@", "#", "$", "%", "^", "&", "*", "(", ")"},
-		Steps:        [16]string{"q", "w", "e", "r", "t", "y", "u", "i", "a", "s", "d", "f", "g", "h", "j", "k"},
-		StepsToggle:  [16]string{"Q", "W", "E", "R", "T", "Y", "U", "I", "A", "S", "D", "F", "G", "H", "J", "K"},
-		ParamMode:    "tab",
-		PatternMode:  "`",
-		AddTrack:     "=",
-		RemoveTrack:  "-",
-		AddStep:      "+",
-		RemoveStep:   "_",
-		CopyStep:     "ctrl+c",
-		PasteStep:    "ctrl+v",
-		PreviousStep: ",",
-		NextStep:     ".",
-		PageUp:       "p",
-		PageDown:     ";",
-		TempoUp:      "shift+up",
-		TempoDown:    "shift+down",
-		AddParam:     "ctrl+up",
-		RemoveParam:  "ctrl+down",
-		Validate:     "enter",
-		Left:         "left",
-		Right:        "right",
-		Up:           "up",
-		Down:         "down",
-		Help:         "?",
+		Tracks:        [10]string{"1", "2", "3", "4", "5", "6", "7", "8", "9", "0"},
+		TracksToggle:  [10]string{"!", "@", "#", "$", "%", "^", "&", "*", "(", ")"},
+		Steps:         [16]string{"q", "w", "e", "r", "t", "y", "u", "i", "a", "s", "d", "f", "g", "h", "j", "k"},
+		StepsToggle:   [16]string{"Q", "W", "E", "R", "T", "Y", "U", "I", "A", "S", "D", "F", "G", "H", "J", "K"},
+		ParamMode:     "tab",
+		PatternMode:   "`",
+		AddTrack:      "=",
+		RemoveTrack:   "-",
+		AddStep:       "+",
+		RemoveStep:    "_",
+		CopyStep:      "ctrl+c",
+		PasteStep:     "ctrl+v",
+		ClearStep:     "ctrl+x",
+		CopyTrack:     "alt+c",
+		PasteTrack:    "alt+v",
+		ClearTrack:    "alt+x",
+		RotateLeft:    "alt+left",
+		RotateRight:   "alt+right",
+		Reverse:       "alt+r",
+		Invert:        "alt+i",
+		Double:        "alt+d",
+		Halve:         "alt+h",
+		Randomize:     "ctrl+g",
+		SelectRange:   "ctrl+r",
+		SelectEvery:   "ctrl+n",
+		SelectActive:  "ctrl+a",
+		EditMode:      "ctrl+e",
+		Undo:          "ctrl+z",
+		Redo:          "ctrl+y",
+		PreviousStep:  ",",
+		NextStep:      ".",
+		PageUp:        "p",
+		PageDown:      ";",
+		TempoUp:       "shift+up",
+		TempoDown:     "shift+down",
+		FineTempoUp:   "alt+up",
+		FineTempoDown: "alt+down",
+		TapTempo:      "b",
+		NudgeUp:       "shift+right",
+		NudgeDown:     "shift+left",
+		TempoEntry:    "ctrl+t",
+		ClockStats:    "ctrl+k",
+		Settings:      "ctrl+o",
+		MidiLearn:     "ctrl+l",
+		Export:        "ctrl+w",
+		AddParam:      "ctrl+up",
+		RemoveParam:   "ctrl+down",
+		Validate:      "enter",
+		Left:          "left",
+		Right:         "right",
+		Up:            "up",
+		Down:          "down",
+		Help:          "?",
 	}
 }
 
@@ -132,31 +240,58 @@ func NewDefaultQwertyKeyMap() KeyMap {
 // keyboards.
 func NewDefaultQwertyMacKeyMap() KeyMap {
 	return KeyMap{
-		Tracks:       [10]string{"1", "2", "3", "4", "5", "6", "7", "8", "9", "0"},
-		TracksToggle: [10]string{"!", "@", "#", "$", "%", "^", "&", "*", "(", ")"},
-		Steps:        [16]string{"q", "w", "e", "r", "t", "y", "u", "i", "a", "s", "d", "f", "g", "h", "j", "k"},
-		StepsToggle:  [16]string{"Q", "W", "E", "R", "T", "Y", "U", "I", "A", "S", "D", "F", "G", "H", "J", "K"},
-		ParamMode:    "tab",
-		PatternMode:  "§",
-		AddTrack:     "=",
-		RemoveTrack:  "-",
-		AddStep:      "+",
-		RemoveStep:   "_",
-		CopyStep:     "ctrl+c",
-		PasteStep:    "ctrl+v",
-		PreviousStep: ",",
-		NextStep:     ".",
-		PageUp:       "p",
-		PageDown:     ";",
-		TempoUp:      "shift+up",
-		TempoDown:    "shift+down",
-		AddParam:     "ctrl+up",
-		RemoveParam:  "ctrl+down",
-		Validate:     "enter",
-		Left:         "left",
-		Right:        "right",
-		Up:           "up",
-		Down:         "down",
-		Help:         "?",
+		Tracks:        [10]string{"1", "2", "3", "4", "5", "6", "7", "8", "9", "0"},
+		TracksToggle:  [10]string{"!", "@", "#", "$", "%", "^", "&", "*", "(", ")"},
+		Steps:         [16]string{"q", "w", "e", "r", "t", "y", "u", "i", "a", "s", "d", "f", "g", "h", "j", "k"},
+		StepsToggle:   [16]string{"Q", "W", "E", "R", "T", "Y", "U", "I", "A", "S", "D", "F", "G", "H", "J", "K"},
+		ParamMode:     "tab",
+		PatternMode:   "§",
+		AddTrack:      "=",
+		RemoveTrack:   "-",
+		AddStep:       "+",
+		RemoveStep:    "_",
+		CopyStep:      "ctrl+c",
+		PasteStep:     "ctrl+v",
+		ClearStep:     "ctrl+x",
+		CopyTrack:     "alt+c",
+		PasteTrack:    "alt+v",
+		ClearTrack:    "alt+x",
+		RotateLeft:    "alt+left",
+		RotateRight:   "alt+right",
+		Reverse:       "alt+r",
+		Invert:        "alt+i",
+		Double:        "alt+d",
+		Halve:         "alt+h",
+		Randomize:     "ctrl+g",
+		SelectRange:   "ctrl+r",
+		SelectEvery:   "ctrl+n",
+		SelectActive:  "ctrl+a",
+		EditMode:      "ctrl+e",
+		Undo:          "ctrl+z",
+		Redo:          "ctrl+y",
+		PreviousStep:  ",",
+		NextStep:      ".",
+		PageUp:        "p",
+		PageDown:      ";",
+		TempoUp:       "shift+up",
+		TempoDown:     "shift+down",
+		FineTempoUp:   "alt+up",
+		FineTempoDown: "alt+down",
+		TapTempo:      "b",
+		NudgeUp:       "shift+right",
+		NudgeDown:     "shift+left",
+		TempoEntry:    "ctrl+t",
+		ClockStats:    "ctrl+k",
+		Settings:      "ctrl+o",
+		MidiLearn:     "ctrl+l",
+		Export:        "ctrl+w",
+		AddParam:      "ctrl+up",
+		RemoveParam:   "ctrl+down",
+		Validate:      "enter",
+		Left:          "left",
+		Right:         "right",
+		Up:            "up",
+		Down:          "down",
+		Help:          "?",
 	}
 }
@@ -0,0 +1,28 @@
+package filesystem
+
+// Metronome holds the metronome settings. The click is sent on every
+// quarter note to the given device (by name) and channel, using AccentNote
+// on the first beat of each bar.
+// CountIn is the number of bars clicked before starting to play.
+type Metronome struct {
+	Enabled    bool   `json:"enabled"`
+	Device     string `json:"device"`
+	Channel    uint8  `json:"channel"`
+	Note       uint8  `json:"note"`
+	AccentNote uint8  `json:"accent_note"`
+	Velocity   uint8  `json:"velocity"`
+	CountIn    int    `json:"count_in"`
+}
+
+// NewDefaultMetronome returns the default metronome settings: general midi
+// wood blocks on the drums channel.
+func NewDefaultMetronome() Metronome {
+	return Metronome{
+		Enabled:    false,
+		Channel:    9,
+		Note:       77,
+		AccentNote: 76,
+		Velocity:   100,
+		CountIn:    0,
+	}
+}
@@ -0,0 +1,22 @@
+package filesystem
+
+// OSC represents the OSC settings.
+// Port holds the UDP port to listen on. The server is disabled when 0.
+// Clients holds the addresses (host:port) that receive the sequencer state,
+// along with the ones that register at runtime.
+// Outputs holds the osc destinations that can be selected as track devices.
+type OSC struct {
+	Port    int         `json:"port"`
+	Clients []string    `json:"clients"`
+	Outputs []OSCOutput `json:"outputs"`
+}
+
+// OSCOutput represents an osc destination (host:port address) that tracks
+// can send their notes and controls to, instead of a midi device. Prefix is
+// prepended to the message addresses, e.g. "/synth" sends the notes to
+// "/synth/note".
+type OSCOutput struct {
+	Name    string `json:"name"`
+	Address string `json:"address"`
+	Prefix  string `json:"prefix"`
+}
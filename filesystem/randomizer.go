@@ -0,0 +1,36 @@
+package filesystem
+
+// Randomizer holds the constraints used to generate random steps on a track.
+// Using the same seed and constraints always generates the same steps.
+type Randomizer struct {
+	Seed           int64  `json:"seed"`
+	Density        int    `json:"density"`
+	Root           uint8  `json:"root"`
+	Scale          string `json:"scale"`
+	MinNote        uint8  `json:"min_note"`
+	MaxNote        uint8  `json:"max_note"`
+	MinVelocity    uint8  `json:"min_velocity"`
+	MaxVelocity    uint8  `json:"max_velocity"`
+	MinLength      int    `json:"min_length"`
+	MaxLength      int    `json:"max_length"`
+	MinProbability int    `json:"min_probability"`
+	MaxProbability int    `json:"max_probability"`
+}
+
+// NewDefaultRandomizer returns the default randomizer constraints.
+func NewDefaultRandomizer() Randomizer {
+	return Randomizer{
+		Seed:           1,
+		Density:        50,
+		Root:           0,
+		Scale:          "major",
+		MinNote:        48,
+		MaxNote:        72,
+		MinVelocity:    80,
+		MaxVelocity:    120,
+		MinLength:      6,
+		MaxLength:      12,
+		MinProbability: 100,
+		MaxProbability: 100,
+	}
+}
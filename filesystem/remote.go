@@ -0,0 +1,24 @@
+package filesystem
+
+// Remote represents the remote control settings.
+// Input holds the name of the midi input port receiving the MIDI Machine
+// Control commands and the Program Changes, e.g. from a DAW or a foot
+// controller. The remote control is disabled when empty.
+// DeviceID holds the MMC device id to respond to, 127 for all the commands.
+// Channel holds the channel (0 to 15) of the Program Changes that select the
+// patterns: program 0 selects the first pattern, and so on. They're ignored
+// when -1.
+type Remote struct {
+	Input    string `json:"input"`
+	DeviceID uint8  `json:"device_id"`
+	Channel  int    `json:"channel"`
+}
+
+// NewDefaultRemote returns the default remote control settings: all the MMC
+// commands and the Program Changes of the first channel.
+func NewDefaultRemote() Remote {
+	return Remote{
+		DeviceID: 127,
+		Channel:  0,
+	}
+}
@@ -0,0 +1,16 @@
+package filesystem
+
+// Thru represents the midi thru settings.
+// Inputs holds the names of the midi input ports whose messages are merged
+// and forwarded to the active track device, on its channel. The thru is
+// disabled when empty.
+// Messages holds the forwarded message types: "note", "cc", "program",
+// "pitchbend" and "aftertouch" (all of them when empty).
+// Output holds the name of a midi device to forward the messages to instead
+// of the active track device, so that they aren't merged with the sequencer
+// output. They're still sent on the active track channel.
+type Thru struct {
+	Inputs   []string `json:"inputs"`
+	Messages []string `json:"messages"`
+	Output   string   `json:"output"`
+}
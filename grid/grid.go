@@ -0,0 +1,345 @@
+// Package grid drives the sequencer from an 8x8 grid controller (e.g. a
+// Launchpad), and mirrors the sequencer state on the controller LEDs. The
+// controller messages are described by a profile (check
+// filesystem.GridProfile).
+//
+// The pads are laid out from the top:
+//
+//	rows 1 to 4   32 steps of the selected track (toggle)
+//	rows 5 and 6  patterns 1 to 16 (load, at the end of the current one when playing)
+//	row 7         tracks 1 to 8 (select, along with the midi thru track)
+//	row 8         tracks 1 to 8 (mute or unmute)
+//
+// The page buttons switch between the pages of 32 steps of the selected
+// track.
+package grid
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"sektron/filesystem"
+	"sektron/midi"
+	"sektron/sequencer"
+)
+
+const (
+	size = 8
+
+	stepRows     = 4
+	stepsPerPage = stepRows * size
+	patternRow   = stepRows
+	patternRows  = 2
+	trackRow     = patternRow + patternRows
+	muteRow      = trackRow + 1
+
+	// feedbackFrequency is how often the sequencer state is checked for
+	// LEDs to update. It's short enough to follow the playhead at high
+	// tempos.
+	feedbackFrequency = 10 * time.Millisecond
+)
+
+// Grid listens to a grid controller and updates its LEDs.
+type Grid struct {
+	seq     sequencer.Sequencer
+	midi    midi.Midi
+	profile filesystem.GridProfile
+	device  int
+	pads    map[uint8]int
+	stop    func()
+	done    chan struct{}
+	closed  chan struct{}
+
+	// track and page are the selected track and steps page, and leds the
+	// last values sent to the controller. When both are needed, the
+	// sequencer lock is taken first.
+	mu    sync.Mutex
+	track int
+	page  int
+	leds  map[led]uint8
+}
+
+// led identifies a pad or button LED by its message ("cc" or "note") and
+// number.
+type led struct {
+	message string
+	number  uint8
+}
+
+// Start opens the grid controller of the given settings.
+func Start(m midi.Midi, config filesystem.Grid, seq sequencer.Sequencer) (*Grid, error) {
+	profile, err := findProfile(config)
+	if err != nil {
+		return nil, err
+	}
+	input := index(inputNames(m), config.Input)
+	if input < 0 {
+		return nil, fmt.Errorf("grid midi input %q not found", config.Input)
+	}
+	output := config.Output
+	if output == "" {
+		output = config.Input
+	}
+	device := index(m.Names(), output)
+	if device < 0 {
+		return nil, fmt.Errorf("grid midi output %q not found", output)
+	}
+	init, err := hex.DecodeString(strings.ReplaceAll(profile.Init, " ", ""))
+	if err != nil {
+		return nil, fmt.Errorf("invalid grid profile %s init: %w", profile.Name, err)
+	}
+
+	g := &Grid{
+		seq:     seq,
+		midi:    m,
+		profile: profile,
+		device:  device,
+		pads:    map[uint8]int{},
+		done:    make(chan struct{}),
+		closed:  make(chan struct{}),
+		leds:    map[led]uint8{},
+	}
+	for row := 0; row < size; row++ {
+		for col := 0; col < size; col++ {
+			g.pads[uint8(profile.Origin+row*profile.RowStep+col)] = row*size + col
+		}
+	}
+	if len(init) > 0 {
+		m.SendSysEx(device, init)
+	}
+	g.stop, err = m.ListenControllers(input, g.receive)
+	if err != nil {
+		return nil, err
+	}
+	go g.sendFeedback()
+	return g, nil
+}
+
+// Close stops listening to the controller and turns its LEDs off.
+func (g *Grid) Close() {
+	g.stop()
+	close(g.done)
+	<-g.closed
+	off := g.profile.Colors["off"]
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for l, value := range g.leds {
+		if value != off {
+			g.send(l, off)
+		}
+	}
+}
+
+// findProfile returns the profile of the given settings, the first default
+// profile if none is set.
+func findProfile(config filesystem.Grid) (filesystem.GridProfile, error) {
+	if config.Profile == "" {
+		return filesystem.NewDefaultGridProfiles()[0], nil
+	}
+	for _, profile := range append(config.Profiles, filesystem.NewDefaultGridProfiles()...) {
+		if profile.Name == config.Profile {
+			return profile, nil
+		}
+	}
+	return filesystem.GridProfile{}, fmt.Errorf("grid profile %q not found", config.Profile)
+}
+
+func inputNames(m midi.Midi) []string {
+	var names []string
+	for _, in := range m.Inputs() {
+		names = append(names, in.String())
+	}
+	return names
+}
+
+func index(names []string, name string) int {
+	for i, n := range names {
+		if n == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// receive applies the pad and button presses. Releases are ignored.
+func (g *Grid) receive(msg midi.ControllerMessage) {
+	if msg.Type == midi.ControllerNoteOff || msg.Value == 0 || msg.Channel != g.profile.Channel {
+		return
+	}
+	message := "note"
+	if msg.Type == midi.ControllerCC {
+		message = "cc"
+	}
+	for action, button := range g.profile.Buttons {
+		if button.Message == message && button.Number == msg.Number {
+			g.press(action)
+			return
+		}
+	}
+	if pad, ok := g.pads[msg.Number]; ok && message == "note" {
+		g.pressPad(pad/size, pad%size)
+	}
+}
+
+func (g *Grid) press(action string) {
+	g.seq.Lock()
+	defer g.seq.Unlock()
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	switch action {
+	case "play":
+		g.seq.TogglePlay()
+	case "page_up":
+		g.page = (g.page + 1) % g.pagesNb()
+	case "page_down":
+		g.page = (g.page - 1 + g.pagesNb()) % g.pagesNb()
+	}
+}
+
+func (g *Grid) pressPad(row, col int) {
+	g.seq.Lock()
+	defer g.seq.Unlock()
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	tracks := g.seq.Tracks()
+	switch {
+	case row < patternRow:
+		step := g.page*stepsPerPage + row*size + col
+		if g.track < len(tracks) && step < len(tracks[g.track].Steps()) {
+			g.seq.ToggleStep(g.track, step)
+		}
+	case row < trackRow:
+		pattern := (row-patternRow)*size + col
+		if pattern >= len(g.seq.Patterns()) {
+			return
+		}
+		if g.seq.IsPlaying() {
+			g.seq.ChainNow(pattern)
+		} else {
+			g.seq.Save()
+			g.seq.Load(pattern)
+		}
+	case row == trackRow:
+		if col < len(tracks) {
+			g.track = col
+			g.page = 0
+			g.seq.SetThruTrack(col)
+		}
+	case row == muteRow:
+		if col < len(tracks) {
+			g.seq.ToggleTrack(col)
+		}
+	}
+}
+
+// pagesNb returns the number of steps pages of the selected track.
+func (g *Grid) pagesNb() int {
+	tracks := g.seq.Tracks()
+	if g.track >= len(tracks) {
+		return 1
+	}
+	return (len(tracks[g.track].Steps())-1)/stepsPerPage + 1
+}
+
+func (g *Grid) sendFeedback() {
+	defer close(g.closed)
+	ticker := time.NewTicker(feedbackFrequency)
+	defer ticker.Stop()
+	for {
+		g.seq.Lock()
+		g.mu.Lock()
+		for l, value := range g.state() {
+			if sent, ok := g.leds[l]; !ok || sent != value {
+				g.leds[l] = value
+				g.send(l, value)
+			}
+		}
+		g.mu.Unlock()
+		g.seq.Unlock()
+
+		select {
+		case <-g.done:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (g *Grid) send(l led, value uint8) {
+	if l.message == "cc" {
+		g.midi.ControlChange(g.device, g.profile.Channel, l.number, value)
+		return
+	}
+	g.midi.NoteOn(g.device, g.profile.Channel, l.number, value)
+}
+
+// state returns the LED values describing the sequencer state. It keeps the
+// selected track and page in range, as tracks and steps can be removed from
+// elsewhere.
+func (g *Grid) state() map[led]uint8 {
+	colors := g.profile.Colors
+	leds := map[led]uint8{}
+	pad := func(row, col int, color string) {
+		leds[led{"note", uint8(g.profile.Origin + row*g.profile.RowStep + col)}] = colors[color]
+	}
+
+	tracks := g.seq.Tracks()
+	if g.track >= len(tracks) {
+		g.track = len(tracks) - 1
+	}
+	if g.page >= g.pagesNb() {
+		g.page = 0
+	}
+	steps := tracks[g.track].Steps()
+	current := tracks[g.track].CurrentStep()
+	for i := 0; i < stepsPerPage; i++ {
+		step := g.page*stepsPerPage + i
+		color := "off"
+		switch {
+		case step >= len(steps):
+		case step == current && g.seq.IsPlaying():
+			color = "playhead"
+		case steps[step].IsActive():
+			color = "step"
+		}
+		pad(i/size, i%size, color)
+	}
+
+	for i := 0; i < patternRows*size; i++ {
+		color := "off"
+		switch {
+		case i == g.seq.ActivePattern():
+			color = "active_pattern"
+		case i < len(g.seq.Patterns()):
+			color = "pattern"
+		}
+		pad(patternRow+i/size, i%size, color)
+	}
+
+	for i := 0; i < size; i++ {
+		selectColor, muteColor := "off", "off"
+		if i < len(tracks) {
+			selectColor, muteColor = "track", "track"
+			if i == g.track {
+				selectColor = "selected"
+			}
+			if !tracks[i].IsActive() {
+				muteColor = "muted"
+			}
+		}
+		pad(trackRow, i, selectColor)
+		pad(muteRow, i, muteColor)
+	}
+
+	if button, ok := g.profile.Buttons["play"]; ok {
+		color := "off"
+		if g.seq.IsPlaying() {
+			color = "play"
+		}
+		leds[led{button.Message, button.Number}] = colors[color]
+	}
+	return leds
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"sektron/filesystem"
+	"sektron/sequencer"
+)
+
+// runHeadless plays the given pattern, then the chained ones, without the
+// terminal ui until SIGINT or SIGTERM is received. It then stops the playing
+// notes and saves the active pattern.
+// When synced to an external clock, the master starts and stops playing.
+func runHeadless(seq sequencer.Sequencer, pattern int, chain []int, autoplay bool) {
+	seq.Lock()
+	seq.Load(pattern)
+	for _, p := range chain {
+		seq.Chain(p)
+	}
+	if autoplay {
+		seq.TogglePlay()
+	}
+	seq.Unlock()
+	log.Printf("playing pattern %d", pattern+1)
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	sig := <-signals
+	log.Printf("received %s, stopping", sig)
+
+	seq.Lock()
+	defer seq.Unlock()
+	if seq.IsPlaying() {
+		seq.TogglePlay()
+	}
+	seq.Reset()
+	seq.Save()
+}
+
+// selectPatterns returns the pattern (starting from 1) to play, the last
+// active one if 0, and the indexes of the patterns to chain.
+func selectPatterns(bank filesystem.Bank, pattern int, chain string) (int, []int, error) {
+	active := bank.Active
+	if pattern < 0 || pattern > len(bank.Patterns) {
+		return 0, nil, fmt.Errorf("invalid pattern %d (1-%d)", pattern, len(bank.Patterns))
+	} else if pattern > 0 {
+		active = pattern - 1
+	}
+	chained, err := parsePatterns(chain, len(bank.Patterns))
+	if err != nil {
+		return 0, nil, err
+	}
+	return active, chained, nil
+}
+
+// parsePatterns parses a comma separated list of pattern numbers (starting
+// from 1) and returns their indexes.
+func parsePatterns(list string, patternsNb int) ([]int, error) {
+	var patterns []int
+	if list == "" {
+		return patterns, nil
+	}
+	for _, item := range strings.Split(list, ",") {
+		pattern, err := strconv.Atoi(strings.TrimSpace(item))
+		if err != nil || pattern < 1 || pattern > patternsNb {
+			return nil, fmt.Errorf("invalid pattern %q (1-%d)", item, patternsNb)
+		}
+		patterns = append(patterns, pattern-1)
+	}
+	return patterns, nil
+}
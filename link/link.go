@@ -0,0 +1,391 @@
+// Package link provides a minimal Ableton Link peer, allowing to share the
+// tempo and beat phase with other peers on the local network.
+//
+// Peers are discovered with the Link discovery protocol: each peer
+// periodically sends its state (session, timeline and measurement endpoint)
+// to a multicast group, and answers the new peers with its state.
+//
+// The session timelines are based on a ghost clock, shared by the peers of
+// the session (check measurement.go). When a peer of another session shows
+// up, we measure its ghost clock and join its session if it's older than
+// ours. Within a session, the timeline with the latest beat origin wins.
+//
+// Unlike the reference implementation, the start/stop state isn't shared
+// and only the IPv4 address of the default interface is advertised.
+package link
+
+import (
+	"bytes"
+	"crypto/rand"
+	"net"
+	"sync"
+	"time"
+)
+
+const (
+	// Peers are forgotten if they don't send their state for ttl seconds.
+	ttl uint8 = 5
+
+	// Each peer sends its state at this interval.
+	broadcastInterval = 250 * time.Millisecond
+
+	// The ghost clock of our session is measured again at this interval, to
+	// follow the drift of the host clocks.
+	remeasureInterval = 30 * time.Second
+
+	// When another session shows up, we join it if its ghost time is ahead
+	// of ours by more than sessionEps. Within sessionEps, the session with
+	// the lowest id wins.
+	sessionEps = 500 * time.Millisecond
+
+	maxMessageSize = 512
+)
+
+var multicastAddr = &net.UDPAddr{IP: net.IPv4(224, 76, 78, 75), Port: 20808}
+
+// Link provides a way to share the tempo and phase with other peers.
+type Link interface {
+	Tempo() float64
+	SetTempo(tempo float64)
+	Peers() int
+	BeatAt(t time.Time) float64
+	TimeAt(beat float64) time.Time
+	Close()
+}
+
+// link contains the peer state. It's modified by the receiving and
+// measuring goroutines, hence the mutex.
+type link struct {
+	mu       sync.Mutex
+	node     nodeID
+	session  nodeID
+	timeline timeline
+	peers    map[nodeID]peer
+
+	// The host time is the time elapsed since start, in microseconds. The
+	// ghost time is the host time plus the session offset.
+	start  time.Time
+	offset int64
+
+	// measured holds the sessions being measured or measured but not
+	// joined, so that they are measured only once.
+	measured map[nodeID]struct{}
+
+	// onChange is called when the timeline is changed by another peer.
+	onChange func()
+
+	receiver     *net.UDPConn
+	sender       *net.UDPConn
+	endpoint     *net.UDPConn
+	endpointAddr *net.UDPAddr
+	done         chan struct{}
+}
+
+// peer holds the last known state of another peer.
+type peer struct {
+	session  nodeID
+	timeline timeline
+	endpoint *net.UDPAddr
+	expires  time.Time
+}
+
+// New creates a new Link peer with the given tempo and starts sending its
+// state and listening to the other peers. The onChange func is called when
+// the tempo or phase is changed by another peer.
+func New(tempo float64, onChange func()) (Link, error) {
+	receiver, err := net.ListenMulticastUDP("udp4", nil, multicastAddr)
+	if err != nil {
+		return nil, err
+	}
+	sender, err := net.ListenUDP("udp4", nil)
+	if err != nil {
+		receiver.Close()
+		return nil, err
+	}
+	endpoint, err := net.ListenUDP("udp4", nil)
+	if err != nil {
+		receiver.Close()
+		sender.Close()
+		return nil, err
+	}
+
+	l := &link{
+		// We found our own session: the ghost time starts now.
+		timeline:     newTimeline(tempo, 0, 0),
+		peers:        map[nodeID]peer{},
+		start:        time.Now(),
+		measured:     map[nodeID]struct{}{},
+		onChange:     onChange,
+		receiver:     receiver,
+		sender:       sender,
+		endpoint:     endpoint,
+		endpointAddr: advertisedAddr(endpoint.LocalAddr().(*net.UDPAddr).Port),
+		done:         make(chan struct{}),
+	}
+	rand.Read(l.node[:])
+	l.session = l.node
+
+	go l.receive(l.receiver)
+	go l.receive(l.sender)
+	go l.answerPings()
+	go l.broadcast()
+	return l, nil
+}
+
+// advertisedAddr returns the address of the measurement endpoint for the
+// other peers: the address of the interface used to reach the multicast
+// group, with the given port.
+func advertisedAddr(port int) *net.UDPAddr {
+	addr := &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: port}
+	// No packet is sent, dialing only picks the interface.
+	conn, err := net.DialUDP("udp4", nil, multicastAddr)
+	if err != nil {
+		return addr
+	}
+	defer conn.Close()
+	addr.IP = conn.LocalAddr().(*net.UDPAddr).IP
+	return addr
+}
+
+// host returns the host time of the given time.
+func (l *link) host(t time.Time) int64 {
+	return t.Sub(l.start).Microseconds()
+}
+
+// ghost returns the session ghost time of the given time.
+func (l *link) ghost(t time.Time) int64 {
+	return l.host(t) + l.offset
+}
+
+// Tempo returns the session tempo.
+func (l *link) Tempo() float64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.timeline.tempo()
+}
+
+// SetTempo changes the session tempo, keeping the current beat, and sends it
+// to the other peers. The new timeline starts at the current beat, hence
+// its beat origin is later than the one of the previous timeline.
+func (l *link) SetTempo(tempo float64) {
+	l.mu.Lock()
+	now := l.ghost(time.Now())
+	timeline := newTimeline(tempo, l.timeline.beatAt(now), now)
+	timeline.beatOrigin = max(timeline.beatOrigin, l.timeline.beatOrigin+1)
+	l.timeline = timeline
+	l.mu.Unlock()
+	l.send(aliveMessage, multicastAddr)
+}
+
+// Peers returns the number of other peers in the session.
+func (l *link) Peers() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	peers := 0
+	for _, p := range l.peers {
+		if p.session == l.session {
+			peers++
+		}
+	}
+	return peers
+}
+
+// BeatAt returns the session beat at the given time.
+func (l *link) BeatAt(t time.Time) float64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.timeline.beatAt(l.ghost(t))
+}
+
+// TimeAt returns the time of the given session beat.
+func (l *link) TimeAt(beat float64) time.Time {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	host := l.timeline.ghostAt(beat) - l.offset
+	return l.start.Add(time.Duration(host) * time.Microsecond)
+}
+
+// Close tells the other peers we're leaving and stops the goroutines.
+func (l *link) Close() {
+	close(l.done)
+	l.send(byebyeMessage, multicastAddr)
+	l.receiver.Close()
+	l.sender.Close()
+	l.endpoint.Close()
+}
+
+// broadcast periodically sends the peer state, forgets the peers that
+// stopped sending theirs and measures our session ghost clock again.
+func (l *link) broadcast() {
+	ticker := time.NewTicker(broadcastInterval)
+	defer ticker.Stop()
+	lastMeasure := time.Now()
+	for {
+		l.send(aliveMessage, multicastAddr)
+		select {
+		case <-l.done:
+			return
+		case now := <-ticker.C:
+			l.mu.Lock()
+			sessions := map[nodeID]struct{}{}
+			for id, p := range l.peers {
+				if now.After(p.expires) {
+					delete(l.peers, id)
+					continue
+				}
+				sessions[p.session] = struct{}{}
+			}
+			// A session that shows up again is measured again.
+			for session := range l.measured {
+				if _, ok := sessions[session]; !ok {
+					delete(l.measured, session)
+				}
+			}
+			if now.Sub(lastMeasure) > remeasureInterval {
+				lastMeasure = now
+				l.measureSession(l.session)
+			}
+			l.mu.Unlock()
+		}
+	}
+}
+
+// send sends the peer state to the given address: the multicast group or a
+// peer we respond to.
+func (l *link) send(kind messageType, to *net.UDPAddr) {
+	l.mu.Lock()
+	msg := message{
+		kind:     kind,
+		ttl:      ttl,
+		node:     l.node,
+		session:  l.session,
+		timeline: l.timeline,
+		endpoint: l.endpointAddr,
+	}
+	l.mu.Unlock()
+	// Sending errors are ignored, the state is sent again later on.
+	_, _ = l.sender.WriteToUDP(msg.encode(), to)
+}
+
+// receive handles the messages sent by the other peers, to the multicast
+// group or to us in response, until the given connection is closed.
+func (l *link) receive(conn *net.UDPConn) {
+	buf := make([]byte, maxMessageSize)
+	for {
+		n, from, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			select {
+			case <-l.done:
+				return
+			default:
+				continue
+			}
+		}
+		msg, err := decode(buf[:n])
+		if err != nil || msg.node == l.node {
+			continue
+		}
+		if msg.kind == aliveMessage {
+			l.send(responseMessage, from)
+		}
+		if l.update(msg) && l.onChange != nil {
+			l.onChange()
+		}
+	}
+}
+
+// update updates the peers with the received message. It returns true if
+// the peer changed our session timeline. The sessions we don't know yet are
+// measured, to decide whether we join them.
+func (l *link) update(msg message) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if msg.kind == byebyeMessage {
+		delete(l.peers, msg.node)
+		return false
+	}
+	if msg.timeline.microsPerBeat <= 0 {
+		return false
+	}
+	l.peers[msg.node] = peer{
+		session:  msg.session,
+		timeline: msg.timeline,
+		endpoint: msg.endpoint,
+		expires:  time.Now().Add(time.Duration(msg.ttl) * time.Second),
+	}
+
+	if msg.session != l.session {
+		if _, ok := l.measured[msg.session]; !ok {
+			l.measureSession(msg.session)
+		}
+		return false
+	}
+	if msg.timeline.beatOrigin <= l.timeline.beatOrigin {
+		return false
+	}
+	l.timeline = msg.timeline
+	return true
+}
+
+// measureSession starts measuring the ghost clock of the given session with
+// one of its peers, if any, then joins it or updates our offset (check
+// joinSession). It's called with the mutex locked.
+func (l *link) measureSession(session nodeID) {
+	if session == l.node {
+		// We founded the session, our offset is the reference.
+		return
+	}
+	for _, p := range l.peers {
+		if p.session != session || p.endpoint == nil {
+			continue
+		}
+		l.measured[session] = struct{}{}
+		go func() {
+			offset, err := measure(p.endpoint, session, func() int64 {
+				return l.host(time.Now())
+			})
+			if err != nil {
+				// The session is measured again on the next message.
+				l.mu.Lock()
+				delete(l.measured, session)
+				l.mu.Unlock()
+				return
+			}
+			if l.joinSession(session, offset) && l.onChange != nil {
+				l.onChange()
+			}
+		}()
+		return
+	}
+}
+
+// joinSession handles the measured offset of the given session. We join the
+// session if it's older than ours, i.e. if its ghost time is ahead. It
+// returns true if the timeline has changed.
+func (l *link) joinSession(session nodeID, offset int64) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if session == l.session {
+		l.offset = offset
+		return true
+	}
+	eps := sessionEps.Microseconds()
+	diff := offset - l.offset
+	if diff < -eps || (diff <= eps && bytes.Compare(session[:], l.session[:]) > 0) {
+		// The peers of the session will join ours.
+		return false
+	}
+
+	l.session = session
+	l.offset = offset
+	delete(l.measured, session)
+	isSet := false
+	for _, p := range l.peers {
+		if p.session == session && (!isSet || p.timeline.beatOrigin > l.timeline.beatOrigin) {
+			l.timeline, isSet = p.timeline, true
+		}
+	}
+	return true
+}
@@ -0,0 +1,185 @@
+package link
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"net"
+	"slices"
+	"time"
+)
+
+// Each peer has its own host clock. To share a timeline, the peers of a
+// session agree on a ghost clock: each of them maps its host time to the
+// ghost time with an offset (ghost = host + offset). The founder of a
+// session picks its own offset, the others measure it by exchanging pings
+// with a peer of the session, at the endpoint advertised in its state.
+
+const (
+	pingMessage messageType = iota + 1
+	pongMessage
+)
+
+const (
+	// Payload entries keys ('__ht', '__gt' and '_pgt').
+	hostTimeKey      uint32 = 0x5f5f6874
+	ghostTimeKey     uint32 = 0x5f5f6774
+	prevGhostTimeKey uint32 = 0x5f706774
+
+	// The ping payload holds the host time and the previous ghost time at
+	// most.
+	maxPingPayloadSize = 2 * (8 + 8)
+
+	// A measurement ends when enough data points are collected, or fails
+	// after a few unanswered pings.
+	measurementPoints   = 100
+	measurementTimeout  = 50 * time.Millisecond
+	measurementAttempts = 5
+)
+
+// All measurement messages start with the measurement header.
+var measurementHeader = [protocolHeaderSize]byte{'_', 'l', 'i', 'n', 'k', '_', 'v', 1}
+
+// timing holds the values of a measurement message, in microseconds. Zero
+// values are not sent.
+type timing struct {
+	session       nodeID
+	hostTime      int64
+	ghostTime     int64
+	prevGhostTime int64
+}
+
+// encodeMeasurement serializes a measurement message: measurement header,
+// message type, then the payload entries.
+func encodeMeasurement(kind messageType, t timing) []byte {
+	var buf bytes.Buffer
+	buf.Write(measurementHeader[:])
+	buf.WriteByte(byte(kind))
+	if t.session != (nodeID{}) {
+		writeEntry(&buf, sessionKey, t.session[:])
+	}
+	for _, entry := range []struct {
+		key   uint32
+		value int64
+	}{
+		{ghostTimeKey, t.ghostTime},
+		{hostTimeKey, t.hostTime},
+		{prevGhostTimeKey, t.prevGhostTime},
+	} {
+		if entry.value != 0 {
+			writeEntry(&buf, entry.key, binary.BigEndian.AppendUint64(nil, uint64(entry.value)))
+		}
+	}
+	return buf.Bytes()
+}
+
+// decodeMeasurement parses a measurement message. It also returns the raw
+// payload, echoed back in pongs.
+func decodeMeasurement(data []byte) (messageType, timing, []byte, error) {
+	var t timing
+	if len(data) < protocolHeaderSize+1 || !bytes.Equal(data[:protocolHeaderSize], measurementHeader[:]) {
+		return 0, t, nil, errors.New("not a link measurement message")
+	}
+	kind := messageType(data[protocolHeaderSize])
+	payload := data[protocolHeaderSize+1:]
+	err := readEntries(payload, func(key uint32, value []byte) {
+		if key == sessionKey && len(value) == nodeIDSize {
+			copy(t.session[:], value)
+			return
+		}
+		if len(value) != 8 {
+			return
+		}
+		switch key {
+		case hostTimeKey:
+			t.hostTime = int64(binary.BigEndian.Uint64(value))
+		case ghostTimeKey:
+			t.ghostTime = int64(binary.BigEndian.Uint64(value))
+		case prevGhostTimeKey:
+			t.prevGhostTime = int64(binary.BigEndian.Uint64(value))
+		}
+	})
+	return kind, t, payload, err
+}
+
+// answerPings answers the measurement pings of the other peers with our
+// session and ghost time, followed by the ping payload, until the endpoint
+// is closed.
+func (l *link) answerPings() {
+	buf := make([]byte, maxMessageSize)
+	for {
+		n, from, err := l.endpoint.ReadFromUDP(buf)
+		if err != nil {
+			select {
+			case <-l.done:
+				return
+			default:
+				continue
+			}
+		}
+		kind, _, payload, err := decodeMeasurement(buf[:n])
+		if err != nil || kind != pingMessage || len(payload) > maxPingPayloadSize {
+			continue
+		}
+		l.mu.Lock()
+		pong := encodeMeasurement(pongMessage, timing{
+			session:   l.session,
+			ghostTime: l.ghost(time.Now()),
+		})
+		l.mu.Unlock()
+		_, _ = l.endpoint.WriteToUDP(append(pong, payload...), from)
+	}
+}
+
+// measure exchanges pings with the peer answering at the given endpoint and
+// returns the offset between our host time and the ghost time of the given
+// session.
+//
+// Each pong gives the peer ghost time between the sending of the ping and
+// the reception of the pong, i.e. around their middle. As each ping also
+// holds the ghost time of the previous pong, each exchange gives two data
+// points. The offset is their median.
+func measure(endpoint *net.UDPAddr, session nodeID, host func() int64) (int64, error) {
+	conn, err := net.ListenUDP("udp4", nil)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+
+	ping := timing{hostTime: host()}
+	var points []int64
+	buf := make([]byte, maxMessageSize)
+	for attempts := 0; attempts < measurementAttempts; {
+		if _, err := conn.WriteToUDP(encodeMeasurement(pingMessage, ping), endpoint); err != nil {
+			return 0, err
+		}
+		conn.SetReadDeadline(time.Now().Add(measurementTimeout))
+		n, _, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			attempts++
+			continue
+		}
+		kind, pong, _, err := decodeMeasurement(buf[:n])
+		if err != nil || kind != pongMessage {
+			continue
+		}
+		if pong.session != session {
+			return 0, errors.New("the peer left the session")
+		}
+
+		now := host()
+		if pong.ghostTime != 0 && pong.hostTime != 0 {
+			points = append(points, pong.ghostTime-(now+pong.hostTime)/2)
+			if pong.prevGhostTime != 0 {
+				points = append(points, (pong.ghostTime+pong.prevGhostTime)/2-pong.hostTime)
+			}
+		}
+		if len(points) > measurementPoints {
+			slices.Sort(points)
+			return points[len(points)/2], nil
+		}
+		ping = timing{hostTime: now, prevGhostTime: pong.ghostTime}
+		attempts = 0
+	}
+	return 0, errors.New("the peer doesn't answer")
+}
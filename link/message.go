@@ -0,0 +1,132 @@
+package link
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"net"
+)
+
+type messageType uint8
+
+const (
+	aliveMessage messageType = iota + 1
+	responseMessage
+	byebyeMessage
+)
+
+const (
+	// Payload entries keys ('tmln', 'sess' and 'mep4').
+	timelineKey uint32 = 0x746d6c6e
+	sessionKey  uint32 = 0x73657373
+	endpointKey uint32 = 0x6d657034
+
+	timelineSize       = 24
+	nodeIDSize         = 8
+	endpointSize       = 6
+	protocolHeaderSize = 8
+	headerSize         = protocolHeaderSize + 4 + nodeIDSize
+)
+
+// All discovery messages start with the protocol header.
+var protocolHeader = [protocolHeaderSize]byte{'_', 'a', 's', 'd', 'p', '_', 'v', 1}
+
+type nodeID [nodeIDSize]byte
+
+// message represents a Link discovery message. It holds the peer state: its
+// node id, the session it belongs to, the session timeline and the address
+// where the peer answers the measurement pings (check measurement.go).
+type message struct {
+	kind     messageType
+	ttl      uint8
+	node     nodeID
+	session  nodeID
+	timeline timeline
+	endpoint *net.UDPAddr
+}
+
+// encode serializes the message to the Link discovery wire format:
+// protocol header, message type, ttl, group id, node id, then the payload
+// entries.
+func (m message) encode() []byte {
+	var buf bytes.Buffer
+	buf.Write(protocolHeader[:])
+	buf.WriteByte(byte(m.kind))
+	buf.WriteByte(m.ttl)
+	binary.Write(&buf, binary.BigEndian, uint16(0))
+	buf.Write(m.node[:])
+	if m.kind == byebyeMessage {
+		return buf.Bytes()
+	}
+
+	value := make([]byte, timelineSize)
+	binary.BigEndian.PutUint64(value[0:8], uint64(m.timeline.microsPerBeat))
+	binary.BigEndian.PutUint64(value[8:16], uint64(m.timeline.beatOrigin))
+	binary.BigEndian.PutUint64(value[16:24], uint64(m.timeline.timeOrigin))
+	writeEntry(&buf, timelineKey, value)
+	writeEntry(&buf, sessionKey, m.session[:])
+	if m.endpoint != nil {
+		if ip := m.endpoint.IP.To4(); ip != nil {
+			value := append([]byte{}, ip...)
+			value = binary.BigEndian.AppendUint16(value, uint16(m.endpoint.Port))
+			writeEntry(&buf, endpointKey, value)
+		}
+	}
+	return buf.Bytes()
+}
+
+// decode parses a message from the Link discovery wire format. Unknown
+// payload entries are ignored.
+func decode(data []byte) (message, error) {
+	var m message
+	if len(data) < headerSize || !bytes.Equal(data[:protocolHeaderSize], protocolHeader[:]) {
+		return m, errors.New("not a link discovery message")
+	}
+	data = data[protocolHeaderSize:]
+	m.kind = messageType(data[0])
+	m.ttl = data[1]
+	copy(m.node[:], data[4:4+nodeIDSize])
+
+	err := readEntries(data[4+nodeIDSize:], func(key uint32, value []byte) {
+		switch {
+		case key == timelineKey && len(value) == timelineSize:
+			m.timeline = timeline{
+				microsPerBeat: int64(binary.BigEndian.Uint64(value[0:8])),
+				beatOrigin:    int64(binary.BigEndian.Uint64(value[8:16])),
+				timeOrigin:    int64(binary.BigEndian.Uint64(value[16:24])),
+			}
+		case key == sessionKey && len(value) == nodeIDSize:
+			copy(m.session[:], value)
+		case key == endpointKey && len(value) == endpointSize:
+			m.endpoint = &net.UDPAddr{
+				IP:   net.IPv4(value[0], value[1], value[2], value[3]),
+				Port: int(binary.BigEndian.Uint16(value[4:6])),
+			}
+		}
+	})
+	return m, err
+}
+
+// writeEntry writes a payload entry: its key, the size of its value and the
+// value.
+func writeEntry(buf *bytes.Buffer, key uint32, value []byte) {
+	binary.Write(buf, binary.BigEndian, key)
+	binary.Write(buf, binary.BigEndian, uint32(len(value)))
+	buf.Write(value)
+}
+
+// readEntries calls the given func with the key and value of each payload
+// entry of the given data.
+func readEntries(data []byte, read func(key uint32, value []byte)) error {
+	for len(data) >= 8 {
+		key := binary.BigEndian.Uint32(data[0:4])
+		size := int(binary.BigEndian.Uint32(data[4:8]))
+		data = data[8:]
+		if size > len(data) {
+			return errors.New("malformed link payload")
+		}
+		read(key, data[:size])
+		data = data[size:]
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package link
+
+import (
+	"math"
+	"time"
+)
+
+// timeline maps the session beats to the ghost time, as in Ableton Link: the
+// beat origin (in micro beats) happens at the time origin (in ghost
+// microseconds) and the tempo is stored as a number of microseconds per
+// beat.
+type timeline struct {
+	microsPerBeat int64
+	beatOrigin    int64
+	timeOrigin    int64
+}
+
+func newTimeline(tempo float64, beat float64, ghost int64) timeline {
+	return timeline{
+		microsPerBeat: int64(math.Round(float64(time.Minute/time.Microsecond) / tempo)),
+		beatOrigin:    int64(math.Round(beat * 1e6)),
+		timeOrigin:    ghost,
+	}
+}
+
+// tempo returns the timeline tempo in beats per minute.
+func (t timeline) tempo() float64 {
+	return float64(time.Minute/time.Microsecond) / float64(t.microsPerBeat)
+}
+
+// beatAt returns the beat at the given ghost time.
+func (t timeline) beatAt(ghost int64) float64 {
+	micros := float64(ghost - t.timeOrigin)
+	return (float64(t.beatOrigin) + micros*1e6/float64(t.microsPerBeat)) / 1e6
+}
+
+// ghostAt returns the ghost time of the given beat.
+func (t timeline) ghostAt(beat float64) int64 {
+	micros := (beat*1e6 - float64(t.beatOrigin)) * float64(t.microsPerBeat) / 1e6
+	return t.timeOrigin + int64(math.Round(micros))
+}
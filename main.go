@@ -6,9 +6,14 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"slices"
 
+	"sektron/api"
+	"sektron/engine"
 	"sektron/filesystem"
+	"sektron/grid"
 	"sektron/midi"
+	"sektron/osc"
 	"sektron/sequencer"
 	"sektron/ui"
 
@@ -22,6 +27,22 @@ func main() {
 	configFile := flag.String("config", "config.json", "config file to load or create")
 	keyboard := flag.String("keyboard", "", "keyboard layout (qwerty, qwerty-mac, azerty, azerty-mac)")
 	patternsFile := flag.String("patterns", "patterns.json", "patterns file to load or create")
+	clockInput := flag.String("clock-input", "", "midi input port to sync the clock to (overrides config)")
+	enableLink := flag.Bool("link", false, "join an Ableton Link session (overrides config)")
+	oscPort := flag.Int("osc-port", 0, "udp port to listen to osc messages on (overrides config)")
+	thruInput := flag.String("thru", "", "midi input port to forward to the active track (overrides config)")
+	remoteInput := flag.String("remote", "", "midi input port to receive machine control and program changes from (overrides config)")
+	gridInput := flag.String("grid", "", "midi input port of the grid controller (overrides config)")
+	httpAddress := flag.String("http", "", "address to serve the http api on, e.g. :8080 (overrides config)")
+	headless := flag.Bool("headless", false, "play without the terminal ui until interrupted")
+	pattern := flag.Int("pattern", 0, "pattern to play in headless mode, to export or to import to (default: last active pattern)")
+	chain := flag.String("chain", "", "comma separated patterns to chain in headless mode or to export")
+	exportFile := flag.String("export", "", "render the pattern and its chain to the given midi file, then exit")
+	song := flag.Bool("song", false, "export all the patterns of the bank, in order")
+	seed := flag.Int64("seed", 0, "seed of the step probabilities when exporting (overrides config)")
+	importFile := flag.String("import", "", "import the given midi file to the pattern, then exit")
+	socket := flag.String("socket", "", "control socket to listen on, to attach uis to and send json-rpc requests to")
+	attach := flag.String("attach", "", "attach the ui to the headless sektron listening on the given control socket")
 	version := flag.Bool("version", false, "print current version")
 	flag.Parse()
 
@@ -30,6 +51,13 @@ func main() {
 		os.Exit(0)
 	}
 
+	if *attach != "" {
+		if err := engine.Attach(*attach); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	midi, err := midi.New()
 	if err != nil {
 		log.Fatal(err)
@@ -39,10 +67,202 @@ func main() {
 	config := filesystem.NewConfiguration(*configFile, *keyboard)
 	bank := filesystem.NewBank(*patternsFile)
 
-	seq := sequencer.New(midi, bank)
+	oscOutput, err := osc.NewOutput(config.OSC.Outputs)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer oscOutput.Close()
+
+	seq := sequencer.New(midi, newOutput(midi, oscOutput), bank)
+
+	if *exportFile != "" {
+		patterns := songPatterns(bank)
+		if !*song {
+			active, chained, err := selectPatterns(bank, *pattern, *chain)
+			if err != nil {
+				log.Fatal(err)
+			}
+			patterns = append([]int{active}, chained...)
+		}
+		exportSeed := config.Export.Seed
+		if *seed != 0 {
+			exportSeed = *seed
+		}
+		runExport(seq, *exportFile, patterns, exportSeed)
+		return
+	}
+
+	if *importFile != "" {
+		active, _, err := selectPatterns(bank, *pattern, "")
+		if err != nil {
+			log.Fatal(err)
+		}
+		runImport(seq, *importFile, active)
+		return
+	}
+
+	// By default, the clock is sent to the first midi device.
+	if len(config.Clock.Outputs) == 0 {
+		config.Clock.Outputs = []filesystem.ClockOutput{
+			filesystem.NewClockOutput(midi.Devices()[0].String(), true),
+		}
+		config.Save()
+	}
+	// The clock is already running, the sequencer is set up while holding
+	// its lock (check sequencer.Sequencer).
+	seq.Lock()
+	seq.SetClockOutputs(config.Clock.Outputs)
+	seq.SetMetronome(config.Metronome)
+
+	input := config.Clock.Input
+	if *clockInput != "" {
+		input = *clockInput
+	}
+	if input != "" {
+		if err := syncTo(midi, seq, input); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	thru := config.Thru
+	if *thruInput != "" {
+		thru.Inputs = []string{*thruInput}
+	}
+	if len(thru.Inputs) > 0 {
+		if err := seq.EnableThru(thru); err != nil {
+			log.Fatal(err)
+		}
+		defer locked(seq, seq.DisableThru)
+	}
+
+	remote := config.Remote
+	if *remoteInput != "" {
+		remote.Input = *remoteInput
+	}
+	if remote.Input != "" {
+		if err := seq.EnableRemote(remote); err != nil {
+			log.Fatal(err)
+		}
+		defer locked(seq, seq.DisableRemote)
+	}
+
+	if *enableLink || config.Clock.Link {
+		if err := seq.EnableLink(); err != nil {
+			log.Fatal(err)
+		}
+		defer locked(seq, seq.DisableLink)
+	}
+	seq.Unlock()
 
-	p := tea.NewProgram(ui.New(config, seq))
+	port := config.OSC.Port
+	if *oscPort != 0 {
+		port = *oscPort
+	}
+	if port != 0 {
+		server, err := osc.Listen(port, config.OSC.Clients, seq)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer server.Close()
+	}
+
+	address := config.API.Address
+	if *httpAddress != "" {
+		address = *httpAddress
+	}
+	if address != "" {
+		server, err := api.Listen(address, config.API.Origins, seq)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer server.Close()
+	}
+
+	gridConfig := config.Grid
+	if *gridInput != "" {
+		gridConfig.Input = *gridInput
+	}
+	if gridConfig.Input != "" {
+		g, err := grid.Start(midi, gridConfig, seq)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer g.Close()
+	}
+
+	if *socket != "" {
+		server, err := engine.Listen(*socket, config, seq)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer server.Close()
+		if *headless {
+			log.Printf("listening on %s", *socket)
+		}
+	}
+
+	if *headless {
+		active, chained, err := selectPatterns(bank, *pattern, *chain)
+		if err != nil {
+			log.Fatal(err)
+		}
+		runHeadless(seq, active, chained, input == "")
+		return
+	}
+
+	p := tea.NewProgram(ui.New(&config, seq))
+	stop := listenControllers(midi, p, append([]string{input, remote.Input, gridConfig.Input}, thru.Inputs...)...)
+	defer stop()
 	if _, err := p.Run(); err != nil {
 		log.Fatal(err)
 	}
 }
+
+// listenControllers sends the messages of all the midi inputs but the
+// excluded ones (e.g. the clock input) to the ui, for midi learn. The inputs
+// that can't be opened are skipped. It returns a func that stops listening.
+func listenControllers(m midi.Midi, p *tea.Program, exclude ...string) func() {
+	var stops []func()
+	for i, in := range m.Inputs() {
+		if slices.Contains(exclude, in.String()) {
+			continue
+		}
+		controller := in.String()
+		stop, err := m.ListenControllers(i, func(msg midi.ControllerMessage) {
+			p.Send(ui.ControllerMsg{Controller: controller, Message: msg})
+		})
+		if err != nil {
+			continue
+		}
+		stops = append(stops, stop)
+	}
+	return func() {
+		for _, stop := range stops {
+			stop()
+		}
+	}
+}
+
+// newOutput returns the output of the tracks: the midi devices come first,
+// then the osc destinations.
+func newOutput(m midi.Midi, o *osc.Output) midi.Output {
+	return midi.NewOutputs(m, o)
+}
+
+// locked calls f while holding the sequencer lock.
+func locked(seq sequencer.Sequencer, f func()) {
+	seq.Lock()
+	defer seq.Unlock()
+	f()
+}
+
+// syncTo makes the sequencer follow the clock of the midi input port with the
+// given name.
+func syncTo(m midi.Midi, seq sequencer.Sequencer, input string) error {
+	for i, in := range m.Inputs() {
+		if in.String() == input {
+			return seq.SyncTo(i)
+		}
+	}
+	return fmt.Errorf("midi input %q not found", input)
+}
@@ -0,0 +1,51 @@
+package midi
+
+import gomidi "gitlab.com/gomidi/midi/v2"
+
+// ClockMessageType is the type of a received midi clock message.
+type ClockMessageType uint8
+
+const (
+	ClockPulse ClockMessageType = iota
+	ClockStart
+	ClockStop
+	ClockContinue
+	ClockPosition
+)
+
+// ClockMessage represents a midi clock related message received from an
+// input device. Position holds the Song Position Pointer value (in 16th
+// notes) of ClockPosition messages.
+//
+// Read more: http://midi.teragonaudio.com/tech/midispec/seq.htm
+type ClockMessage struct {
+	Type     ClockMessageType
+	Position uint16
+}
+
+// Inputs returns all in ports.
+func (m *midi) Inputs() gomidi.InPorts {
+	return m.inputs
+}
+
+// ListenClock listens to the Timing Clock, Start, Stop, Continue and Song
+// Position Pointer messages of the given input device. Other messages are
+// ignored. The receive func is called from the listening goroutine.
+// It returns a func that stops listening.
+func (m *midi) ListenClock(input int, receive func(ClockMessage)) (func(), error) {
+	return m.listen(input, func(msg gomidi.Message) {
+		var position uint16
+		switch {
+		case msg.Is(gomidi.TimingClockMsg):
+			receive(ClockMessage{Type: ClockPulse})
+		case msg.Is(gomidi.StartMsg):
+			receive(ClockMessage{Type: ClockStart})
+		case msg.Is(gomidi.StopMsg):
+			receive(ClockMessage{Type: ClockStop})
+		case msg.Is(gomidi.ContinueMsg):
+			receive(ClockMessage{Type: ClockContinue})
+		case msg.GetSPP(&position):
+			receive(ClockMessage{Type: ClockPosition, Position: position})
+		}
+	})
+}
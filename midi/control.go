@@ -12,6 +12,17 @@ const (
 	minPitch   = -8192
 	maxPitch   = 8192
 	resetPitch = 0
+
+	// ControlsCount is the number of controls created by NewControls. The
+	// Control Changes come after the program change, pitch bend and after
+	// touch ones.
+	ControlsCount = firstCC + maxCC + 1
+	firstCC       = 3
+
+	// Indexes of the program change, pitch bend and after touch controls.
+	ProgramControl    = 0
+	PitchbendControl  = 1
+	AfterTouchControl = 2
 )
 
 type msgType uint8
@@ -39,7 +50,7 @@ type Controllable interface {
 //   - Pitchbend
 //   - Aftertouch
 type Control struct {
-	midi Midi
+	output Output
 
 	// We keep a reference to the parent item to known in which device and
 	// channel we need to send the message.
@@ -56,20 +67,20 @@ type Control struct {
 //   - 1 Program Change
 //   - 1 Pichbend
 //   - 1 Aftertouch
-func NewControls(midi Midi, parent Controllable) []Control {
+func NewControls(output Output, parent Controllable) []Control {
 	controls := []Control{
 		{
-			midi:    midi,
+			output:  output,
 			parent:  parent,
 			msgType: programChange,
 		},
 		{
-			midi:    midi,
+			output:  output,
 			parent:  parent,
 			msgType: pitchBend,
 		},
 		{
-			midi:    midi,
+			output:  output,
 			parent:  parent,
 			msgType: afterTouch,
 		},
@@ -77,7 +88,7 @@ func NewControls(midi Midi, parent Controllable) []Control {
 
 	for i := 0; i <= 127; i++ {
 		controls = append(controls, Control{
-			midi:       midi,
+			output:     output,
 			parent:     parent,
 			msgType:    controlChange,
 			controller: uint8(i),
@@ -86,6 +97,12 @@ func NewControls(midi Midi, parent Controllable) []Control {
 	return controls
 }
 
+// CCControl returns the index of the Control Change of the given controller
+// number in the controls created by NewControls.
+func CCControl(controller uint8) int {
+	return firstCC + int(controller)
+}
+
 // Value returns the control value.
 func (c Control) Value() int16 {
 	return c.value
@@ -130,21 +147,21 @@ func (c *Control) Set(value int16) {
 func (c Control) Send() {
 	switch c.msgType {
 	case controlChange:
-		c.midi.ControlChange(
+		c.output.ControlChange(
 			c.parent.Device(),
 			c.parent.Channel(),
 			c.controller,
 			uint8(c.value),
 		)
 	case programChange:
-		c.midi.ProgramChange(
+		c.output.ProgramChange(
 			c.parent.Device(),
 			c.parent.Channel(),
 			uint8(c.value),
 		)
 	case pitchBend:
-		c.midi.Pitchbend(c.parent.Device(), c.parent.Channel(), c.value)
+		c.output.Pitchbend(c.parent.Device(), c.parent.Channel(), c.value)
 	case afterTouch:
-		c.midi.AfterTouch(c.parent.Device(), c.parent.Channel(), uint8(c.value))
+		c.output.AfterTouch(c.parent.Device(), c.parent.Channel(), uint8(c.value))
 	}
 }
@@ -0,0 +1,41 @@
+package midi
+
+import gomidi "gitlab.com/gomidi/midi/v2"
+
+// ControllerMessageType is the type of a message received from a midi
+// controller.
+type ControllerMessageType uint8
+
+const (
+	ControllerCC ControllerMessageType = iota
+	ControllerNoteOn
+	ControllerNoteOff
+)
+
+// ControllerMessage represents a control change or a note received from a
+// midi controller (e.g. a knob box or a pad grid). Number holds the
+// controller or note number, and Value the control value or note velocity.
+type ControllerMessage struct {
+	Type    ControllerMessageType
+	Channel uint8
+	Number  uint8
+	Value   uint8
+}
+
+// ListenControllers listens to the Control Change, Note On and Note Off
+// messages of the given input device. Other messages are ignored. The
+// receive func is called from the listening goroutine.
+// It returns a func that stops listening.
+func (m *midi) ListenControllers(input int, receive func(ControllerMessage)) (func(), error) {
+	return m.listen(input, func(msg gomidi.Message) {
+		var channel, number, value uint8
+		switch {
+		case msg.GetControlChange(&channel, &number, &value):
+			receive(ControllerMessage{Type: ControllerCC, Channel: channel, Number: number, Value: value})
+		case msg.GetNoteStart(&channel, &number, &value):
+			receive(ControllerMessage{Type: ControllerNoteOn, Channel: channel, Number: number, Value: value})
+		case msg.GetNoteEnd(&channel, &number):
+			receive(ControllerMessage{Type: ControllerNoteOff, Channel: channel, Number: number})
+		}
+	})
+}
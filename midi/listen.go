@@ -0,0 +1,72 @@
+package midi
+
+import (
+	"fmt"
+	"sync"
+
+	gomidi "gitlab.com/gomidi/midi/v2"
+)
+
+// listener holds the handlers of a listened input. An input port can only be
+// listened to once, while several features may need its messages (e.g. a
+// DAW sending both the clock and the transport commands).
+type listener struct {
+	stop     func()
+	handlers map[int]func(gomidi.Message)
+	next     int
+}
+
+// listen calls the given handler with the messages received on the input
+// device, System Exclusive ones included. The handlers are called from the
+// listening goroutine.
+// It returns a func that stops listening.
+func (m *midi) listen(input int, handler func(gomidi.Message)) (func(), error) {
+	if input < 0 || input >= len(m.inputs) {
+		return nil, fmt.Errorf("midi input %d not found", input)
+	}
+	m.listenersMu.Lock()
+	defer m.listenersMu.Unlock()
+
+	l, ok := m.listeners[input]
+	if !ok {
+		l = &listener{handlers: map[int]func(gomidi.Message){}}
+		stop, err := gomidi.ListenTo(m.inputs[input], func(msg gomidi.Message, _ int32) {
+			m.listenersMu.Lock()
+			handlers := make([]func(gomidi.Message), 0, len(l.handlers))
+			for _, handler := range l.handlers {
+				handlers = append(handlers, handler)
+			}
+			m.listenersMu.Unlock()
+			for _, handler := range handlers {
+				handler(msg)
+			}
+		}, gomidi.UseSysEx())
+		if err != nil {
+			return nil, err
+		}
+		l.stop = stop
+		m.listeners[input] = l
+	}
+
+	id := l.next
+	l.next++
+	l.handlers[id] = handler
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			m.listenersMu.Lock()
+			delete(l.handlers, id)
+			last := len(l.handlers) == 0
+			if last {
+				delete(m.listeners, input)
+			}
+			m.listenersMu.Unlock()
+			// The port is closed outside of the lock, as the driver may wait
+			// for the running callback.
+			if last {
+				l.stop()
+			}
+		})
+	}, nil
+}
@@ -21,15 +21,21 @@ const (
 
 // Midi provides a way to interct with midi devices.
 type Midi interface {
+	Output
 	Devices() gomidi.OutPorts
-	NoteOn(device int, channel, note, velocity uint8)
-	NoteOff(device int, channel, note uint8)
-	Silence(device int, channel uint8)
-	ControlChange(device int, channel, controller, value uint8)
-	ProgramChange(device int, channel, value uint8)
-	Pitchbend(device int, channel uint8, value int16)
-	AfterTouch(device int, channel, value uint8)
+	Inputs() gomidi.InPorts
 	SendClock(devices []int)
+	SendStart(devices []int)
+	SendStop(devices []int)
+	SendContinue(devices []int)
+	SendSongPosition(devices []int, position uint16)
+	SendSysEx(device int, data []byte)
+	SendQuarterFrame(devices []int, data uint8)
+	SendFullFrame(devices []int, tc TimeCode)
+	ListenClock(input int, receive func(ClockMessage)) (func(), error)
+	ListenControllers(input int, receive func(ControllerMessage)) (func(), error)
+	ListenThru(input int, receive func(ThruMessage)) (func(), error)
+	ListenRemote(input int, receive func(RemoteMessage)) (func(), error)
 	Close()
 }
 
@@ -39,6 +45,12 @@ type midi struct {
 	// devices holds all the midi devices outputs that are returned by gomidi.
 	devices gomidi.OutPorts
 
+	// inputs holds all the midi devices inputs, and listeners the handlers
+	// of the listened ones (check listen.go).
+	inputs      gomidi.InPorts
+	listenersMu sync.Mutex
+	listeners   map[int]*listener
+
 	// Because we want to allow the usage of multiple midi devices at the same
 	// time, we start a goroutine for each device that can receive note trigs.
 	// The wait group is used when closing the midi devices (waits for all
@@ -58,7 +70,9 @@ func New() (Midi, error) {
 		return nil, errors.New("no midi drivers")
 	}
 	midi := &midi{
-		devices: devices,
+		devices:   devices,
+		inputs:    gomidi.GetInPorts(),
+		listeners: map[int]*listener{},
 	}
 	midi.start()
 	return midi, nil
@@ -111,6 +125,15 @@ func (m *midi) Devices() gomidi.OutPorts {
 	return m.devices
 }
 
+// Names returns the out ports names.
+func (m *midi) Names() []string {
+	var names []string
+	for _, device := range m.devices {
+		names = append(names, device.String())
+	}
+	return names
+}
+
 // NoteOn sends a Note On midi meessage to the given device.
 func (m *midi) NoteOn(device int, channel, note, velocity uint8) {
 	m.outputs[device] <- gomidi.NoteOn(channel, note, velocity)
@@ -155,6 +178,41 @@ func (m *midi) SendClock(devices []int) {
 	}
 }
 
+// SendStart sends a Start midi message to given devices.
+func (m *midi) SendStart(devices []int) {
+	for _, device := range devices {
+		m.outputs[device] <- gomidi.Start()
+	}
+}
+
+// SendStop sends a Stop midi message to given devices.
+func (m *midi) SendStop(devices []int) {
+	for _, device := range devices {
+		m.outputs[device] <- gomidi.Stop()
+	}
+}
+
+// SendContinue sends a Continue midi message to given devices.
+func (m *midi) SendContinue(devices []int) {
+	for _, device := range devices {
+		m.outputs[device] <- gomidi.Continue()
+	}
+}
+
+// SendSongPosition sends a Song Position Pointer midi message to given
+// devices. The position is in 16th notes from the beginning of the song.
+func (m *midi) SendSongPosition(devices []int, position uint16) {
+	for _, device := range devices {
+		m.outputs[device] <- gomidi.SPP(position)
+	}
+}
+
+// SendSysEx sends a System Exclusive message to the given device. The data
+// doesn't include the F0 and F7 delimiters.
+func (m *midi) SendSysEx(device int, data []byte) {
+	m.outputs[device] <- gomidi.SysEx(data)
+}
+
 // Close terminates all the device goroutines gracefully.
 func (m *midi) Close() {
 	defer gomidi.CloseDriver()
@@ -0,0 +1,118 @@
+package midi
+
+import "time"
+
+// Output sends the track notes and controls to devices, identified by their
+// index in Names. It's implemented by Midi, and by other backends that
+// translate the midi messages to their own protocol (e.g. osc).
+type Output interface {
+	Names() []string
+	NoteOn(device int, channel, note, velocity uint8)
+	NoteOff(device int, channel, note uint8)
+	Silence(device int, channel uint8)
+	ControlChange(device int, channel, controller, value uint8)
+	ProgramChange(device int, channel, value uint8)
+	Pitchbend(device int, channel uint8, value int16)
+	AfterTouch(device int, channel, value uint8)
+}
+
+// LengthOutput is an Output that also takes the note length along with the
+// Note On message, for the backends that have no gate (e.g. osc). The length
+// is 0 for the notes that last until their Note Off.
+type LengthOutput interface {
+	Output
+	NoteOnFor(device int, channel, note, velocity uint8, length time.Duration)
+}
+
+// outputs routes the messages to multiple outputs. The devices of the first
+// output come first, then the ones of the second output, and so on.
+type outputs []Output
+
+// NewOutputs returns an Output that routes the messages to the given outputs.
+func NewOutputs(o ...Output) Output {
+	return outputs(o)
+}
+
+// Names returns the device names of all the outputs.
+func (o outputs) Names() []string {
+	var names []string
+	for _, output := range o {
+		names = append(names, output.Names()...)
+	}
+	return names
+}
+
+// route returns the output of the given device, and the device index within
+// this output.
+func (o outputs) route(device int) (Output, int) {
+	for _, output := range o {
+		count := len(output.Names())
+		if device < count {
+			return output, device
+		}
+		device -= count
+	}
+	return nil, 0
+}
+
+// NoteOn sends a Note On message to the given device.
+func (o outputs) NoteOn(device int, channel, note, velocity uint8) {
+	if output, device := o.route(device); output != nil {
+		output.NoteOn(device, channel, note, velocity)
+	}
+}
+
+// NoteOnFor sends a Note On message to the given device, with the note
+// length if its output is a LengthOutput.
+func (o outputs) NoteOnFor(device int, channel, note, velocity uint8, length time.Duration) {
+	output, device := o.route(device)
+	if output, ok := output.(LengthOutput); ok {
+		output.NoteOnFor(device, channel, note, velocity, length)
+		return
+	}
+	if output != nil {
+		output.NoteOn(device, channel, note, velocity)
+	}
+}
+
+// NoteOff sends a Note Off message to the given device.
+func (o outputs) NoteOff(device int, channel, note uint8) {
+	if output, device := o.route(device); output != nil {
+		output.NoteOff(device, channel, note)
+	}
+}
+
+// Silence stops all the playing notes of the given device channel.
+func (o outputs) Silence(device int, channel uint8) {
+	if output, device := o.route(device); output != nil {
+		output.Silence(device, channel)
+	}
+}
+
+// ControlChange sends a Control Change message to the given device.
+func (o outputs) ControlChange(device int, channel, controller, value uint8) {
+	if output, device := o.route(device); output != nil {
+		output.ControlChange(device, channel, controller, value)
+	}
+}
+
+// ProgramChange sends a Program Change message to the given device.
+func (o outputs) ProgramChange(device int, channel, value uint8) {
+	if output, device := o.route(device); output != nil {
+		output.ProgramChange(device, channel, value)
+	}
+}
+
+// Pitchbend sends a Pitch Bend message to the given device.
+func (o outputs) Pitchbend(device int, channel uint8, value int16) {
+	if output, device := o.route(device); output != nil {
+		output.Pitchbend(device, channel, value)
+	}
+}
+
+// AfterTouch sends an After Touch message to the given device.
+func (o outputs) AfterTouch(device int, channel, value uint8) {
+	if output, device := o.route(device); output != nil {
+		output.AfterTouch(device, channel, value)
+	}
+}
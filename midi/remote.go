@@ -0,0 +1,101 @@
+package midi
+
+import (
+	"time"
+
+	gomidi "gitlab.com/gomidi/midi/v2"
+)
+
+// RemoteMessageType is the type of a received remote control message.
+type RemoteMessageType uint8
+
+const (
+	RemotePlay RemoteMessageType = iota
+	RemoteStop
+	RemoteLocate
+	RemoteProgram
+)
+
+// MIDI Machine Control messages are System Exclusive messages:
+// F0 7F <device id> 06 <command> ... F7
+const (
+	mmcRealtime  = 0x7F
+	mmcCommand   = 0x06
+	mmcStop      = 0x01
+	mmcPlay      = 0x02
+	mmcDeferPlay = 0x03
+	mmcPause     = 0x09
+	mmcLocate    = 0x44
+	mmcAllCall   = 0x7F
+)
+
+// RemoteMessage represents a MIDI Machine Control command or a Program
+// Change received from an input device. DeviceID holds the MMC device the
+// command is sent to (127 for all of them). Position holds the time code of
+// RemoteLocate messages, and Channel and Program the Program Change values.
+//
+// Read more: http://midi.teragonaudio.com/tech/midispec/mmc.htm
+type RemoteMessage struct {
+	Type     RemoteMessageType
+	DeviceID uint8
+	Position time.Duration
+	Channel  uint8
+	Program  uint8
+}
+
+// IsFor returns true if the message is sent to the given MMC device id, or
+// to all of them.
+func (msg RemoteMessage) IsFor(deviceID uint8) bool {
+	return msg.DeviceID == deviceID || msg.DeviceID == mmcAllCall || deviceID == mmcAllCall
+}
+
+// ListenRemote listens to the MIDI Machine Control Play, Deferred Play, Stop,
+// Pause and Locate commands, and to the Program Change messages of the given
+// input device. Other messages are ignored. The receive func is called from
+// the listening goroutine.
+// It returns a func that stops listening.
+func (m *midi) ListenRemote(input int, receive func(RemoteMessage)) (func(), error) {
+	return m.listen(input, func(msg gomidi.Message) {
+		var channel, program uint8
+		var data []byte
+		switch {
+		case msg.GetProgramChange(&channel, &program):
+			receive(RemoteMessage{Type: RemoteProgram, Channel: channel, Program: program})
+		case msg.GetSysEx(&data):
+			if remote, ok := parseMMC(data); ok {
+				receive(remote)
+			}
+		}
+	})
+}
+
+// parseMMC parses the data of a MIDI Machine Control System Exclusive
+// message, without the F0 and F7 delimiters.
+func parseMMC(data []byte) (RemoteMessage, bool) {
+	if len(data) < 4 || data[0] != mmcRealtime || data[2] != mmcCommand {
+		return RemoteMessage{}, false
+	}
+	msg := RemoteMessage{DeviceID: data[1]}
+	switch data[3] {
+	case mmcPlay, mmcDeferPlay:
+		msg.Type = RemotePlay
+	case mmcStop, mmcPause:
+		msg.Type = RemoteStop
+	case mmcLocate:
+		// Locate target: 44 06 01 hr mn sc fr ff, the hours byte holding the
+		// frame rate in bits 5 and 6.
+		if len(data) < 11 || data[4] != 0x06 || data[5] != 0x01 {
+			return RemoteMessage{}, false
+		}
+		fps := []float64{24, 25, 29.97, 30}[(data[6]>>5)&0x03]
+		hours := time.Duration(data[6]&0x1F) * time.Hour
+		minutes := time.Duration(data[7]) * time.Minute
+		seconds := time.Duration(data[8]) * time.Second
+		frames := time.Duration((float64(data[9]) + float64(data[10])/100) / fps * float64(time.Second))
+		msg.Type = RemoteLocate
+		msg.Position = hours + minutes + seconds + frames
+	default:
+		return RemoteMessage{}, false
+	}
+	return msg, true
+}
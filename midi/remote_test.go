@@ -0,0 +1,48 @@
+package midi
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseMMC(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want RemoteMessage
+		ok   bool
+	}{
+		{"play", []byte{0x7F, 0x10, 0x06, 0x02}, RemoteMessage{Type: RemotePlay, DeviceID: 0x10}, true},
+		{"deferred play", []byte{0x7F, 0x7F, 0x06, 0x03}, RemoteMessage{Type: RemotePlay, DeviceID: 0x7F}, true},
+		{"stop", []byte{0x7F, 0x00, 0x06, 0x01}, RemoteMessage{Type: RemoteStop}, true},
+		{"pause", []byte{0x7F, 0x00, 0x06, 0x09}, RemoteMessage{Type: RemoteStop}, true},
+		{
+			"locate 24 fps",
+			[]byte{0x7F, 0x7F, 0x06, 0x44, 0x06, 0x01, 0x00, 0x02, 0x03, 0x0C, 0x00},
+			RemoteMessage{Type: RemoteLocate, DeviceID: 0x7F, Position: 2*time.Minute + 3*time.Second + 500*time.Millisecond},
+			true,
+		},
+		{
+			// The 25 fps rate is in the bits 5 and 6 of the hours byte.
+			"locate 25 fps",
+			[]byte{0x7F, 0x7F, 0x06, 0x44, 0x06, 0x01, 0x21, 0x00, 0x0A, 0x0C, 0x32},
+			RemoteMessage{Type: RemoteLocate, DeviceID: 0x7F, Position: time.Hour + 10*time.Second + 500*time.Millisecond},
+			true,
+		},
+		{
+			"locate 30 fps",
+			[]byte{0x7F, 0x01, 0x06, 0x44, 0x06, 0x01, 0x60, 0x01, 0x00, 0x0F, 0x00},
+			RemoteMessage{Type: RemoteLocate, DeviceID: 0x01, Position: time.Minute + 500*time.Millisecond},
+			true,
+		},
+		{"truncated locate", []byte{0x7F, 0x7F, 0x06, 0x44, 0x06, 0x01, 0x00}, RemoteMessage{}, false},
+		{"unknown command", []byte{0x7F, 0x7F, 0x06, 0x05}, RemoteMessage{}, false},
+		{"not mmc", []byte{0x7E, 0x7F, 0x06, 0x02}, RemoteMessage{}, false},
+	}
+	for _, test := range tests {
+		got, ok := parseMMC(test.data)
+		if ok != test.ok || got != test.want {
+			t.Errorf("%s: parseMMC() = %+v, %t, want %+v, %t", test.name, got, ok, test.want, test.ok)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package midi
+
+import gomidi "gitlab.com/gomidi/midi/v2"
+
+// ThruMessageType is the type of a channel message received to be forwarded
+// to another device.
+type ThruMessageType uint8
+
+const (
+	ThruNoteOn ThruMessageType = iota
+	ThruNoteOff
+	ThruControlChange
+	ThruProgramChange
+	ThruPitchbend
+	ThruAfterTouch
+)
+
+// String returns the message type name used in the thru settings: note,
+// cc, program, pitchbend or aftertouch.
+func (t ThruMessageType) String() string {
+	switch t {
+	case ThruNoteOn, ThruNoteOff:
+		return "note"
+	case ThruControlChange:
+		return "cc"
+	case ThruProgramChange:
+		return "program"
+	case ThruPitchbend:
+		return "pitchbend"
+	default:
+		return "aftertouch"
+	}
+}
+
+// ThruMessage represents a channel message received from an input device.
+// Data1 and Data2 hold the note and velocity, the controller and value, the
+// program or the pressure, depending on the type. Pitchbend holds the
+// pitch bend value.
+type ThruMessage struct {
+	Type      ThruMessageType
+	Channel   uint8
+	Data1     uint8
+	Data2     uint8
+	Pitchbend int16
+}
+
+// ListenThru listens to the channel messages of the given input device. The
+// receive func is called from the listening goroutine.
+// It returns a func that stops listening.
+func (m *midi) ListenThru(input int, receive func(ThruMessage)) (func(), error) {
+	return m.listen(input, func(msg gomidi.Message) {
+		var channel, data1, data2 uint8
+		var relative int16
+		var absolute uint16
+		switch {
+		case msg.GetNoteStart(&channel, &data1, &data2):
+			receive(ThruMessage{Type: ThruNoteOn, Channel: channel, Data1: data1, Data2: data2})
+		case msg.GetNoteEnd(&channel, &data1):
+			receive(ThruMessage{Type: ThruNoteOff, Channel: channel, Data1: data1})
+		case msg.GetControlChange(&channel, &data1, &data2):
+			receive(ThruMessage{Type: ThruControlChange, Channel: channel, Data1: data1, Data2: data2})
+		case msg.GetProgramChange(&channel, &data1):
+			receive(ThruMessage{Type: ThruProgramChange, Channel: channel, Data1: data1})
+		case msg.GetPitchBend(&channel, &relative, &absolute):
+			receive(ThruMessage{Type: ThruPitchbend, Channel: channel, Pitchbend: relative})
+		case msg.GetAfterTouch(&channel, &data1):
+			receive(ThruMessage{Type: ThruAfterTouch, Channel: channel, Data1: data1})
+		}
+	})
+}
+
+// SendTo sends the message to the given device of the output, on the given
+// channel instead of its own one.
+func (msg ThruMessage) SendTo(o Output, device int, channel uint8) {
+	switch msg.Type {
+	case ThruNoteOn:
+		o.NoteOn(device, channel, msg.Data1, msg.Data2)
+	case ThruNoteOff:
+		o.NoteOff(device, channel, msg.Data1)
+	case ThruControlChange:
+		o.ControlChange(device, channel, msg.Data1, msg.Data2)
+	case ThruProgramChange:
+		o.ProgramChange(device, channel, msg.Data1)
+	case ThruPitchbend:
+		o.Pitchbend(device, channel, msg.Pitchbend)
+	case ThruAfterTouch:
+		o.AfterTouch(device, channel, msg.Data1)
+	}
+}
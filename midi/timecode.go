@@ -0,0 +1,141 @@
+package midi
+
+import (
+	"fmt"
+	"time"
+
+	gomidi "gitlab.com/gomidi/midi/v2"
+)
+
+// FrameRate is a MIDI Time Code frame rate. Its value is the rate code sent
+// in the time code hours.
+type FrameRate uint8
+
+const (
+	FrameRate24 FrameRate = iota
+	FrameRate25
+	FrameRate2997Drop
+	FrameRate30
+)
+
+// frameRates holds the frame rates by name, as used in the clock settings.
+var frameRates = map[string]FrameRate{
+	"24":    FrameRate24,
+	"25":    FrameRate25,
+	"29.97": FrameRate2997Drop,
+	"30":    FrameRate30,
+}
+
+// ParseFrameRate returns the frame rate of the given name: "24", "25",
+// "29.97" (drop frame) or "30".
+func ParseFrameRate(name string) (FrameRate, error) {
+	rate, ok := frameRates[name]
+	if !ok {
+		return 0, fmt.Errorf("invalid frame rate %q", name)
+	}
+	return rate, nil
+}
+
+// FrameDuration returns the duration of a frame.
+func (r FrameRate) FrameDuration() time.Duration {
+	switch r {
+	case FrameRate24:
+		return time.Second / 24
+	case FrameRate25:
+		return time.Second / 25
+	case FrameRate2997Drop:
+		return time.Second * 1001 / 30000
+	default:
+		return time.Second / 30
+	}
+}
+
+// TimeCode represents a MIDI Time Code position.
+//
+// Read more: http://midi.teragonaudio.com/tech/mtc.htm
+type TimeCode struct {
+	Hours   uint8
+	Minutes uint8
+	Seconds uint8
+	Frames  uint8
+	Rate    FrameRate
+}
+
+// NewTimeCode returns the time code of the given frame, counted from 0.
+// Drop frame time codes skip the frame numbers 0 and 1 of every minute but
+// every tenth one, so that they follow the clock time.
+func NewTimeCode(frame int, rate FrameRate) TimeCode {
+	fps := 30
+	switch rate {
+	case FrameRate24:
+		fps = 24
+	case FrameRate25:
+		fps = 25
+	case FrameRate2997Drop:
+		// 17982 frames per 10 minutes, 1798 per minute after the first one.
+		tens, rest := frame/17982, frame%17982
+		frame += 18 * tens
+		if rest > 1 {
+			frame += 2 * ((rest - 2) / 1798)
+		}
+	}
+	return TimeCode{
+		Hours:   uint8(frame / (fps * 3600) % 24),
+		Minutes: uint8(frame / (fps * 60) % 60),
+		Seconds: uint8(frame / fps % 60),
+		Frames:  uint8(frame % fps),
+		Rate:    rate,
+	}
+}
+
+// String returns the time code label, hh:mm:ss:ff. Drop frame time codes
+// separate the frames with a semicolon.
+func (tc TimeCode) String() string {
+	separator := ":"
+	if tc.Rate == FrameRate2997Drop {
+		separator = ";"
+	}
+	return fmt.Sprintf("%02d:%02d:%02d%s%02d", tc.Hours, tc.Minutes, tc.Seconds, separator, tc.Frames)
+}
+
+// QuarterFrame returns the data byte of the given quarter frame piece (0 to
+// 7) of the time code.
+func (tc TimeCode) QuarterFrame(piece int) uint8 {
+	var value uint8
+	switch piece {
+	case 0:
+		value = tc.Frames & 0x0F
+	case 1:
+		value = tc.Frames >> 4
+	case 2:
+		value = tc.Seconds & 0x0F
+	case 3:
+		value = tc.Seconds >> 4
+	case 4:
+		value = tc.Minutes & 0x0F
+	case 5:
+		value = tc.Minutes >> 4
+	case 6:
+		value = tc.Hours & 0x0F
+	case 7:
+		value = tc.Hours>>4 | uint8(tc.Rate)<<1
+	}
+	return uint8(piece)<<4 | value&0x0F
+}
+
+// SendQuarterFrame sends a MIDI Time Code Quarter Frame message to the
+// given devices (check TimeCode.QuarterFrame).
+func (m *midi) SendQuarterFrame(devices []int, data uint8) {
+	for _, device := range devices {
+		m.outputs[device] <- gomidi.MTC(data)
+	}
+}
+
+// SendFullFrame sends a MIDI Time Code Full Frame message to the given
+// devices, e.g. when the position jumps.
+func (m *midi) SendFullFrame(devices []int, tc TimeCode) {
+	data := []byte{0x7F, 0x7F, 0x01, 0x01, uint8(tc.Rate)<<5 | tc.Hours, tc.Minutes, tc.Seconds, tc.Frames}
+	for _, device := range devices {
+		m.outputs[device] <- gomidi.SysEx(data)
+	}
+}
@@ -0,0 +1,44 @@
+package midi
+
+import "testing"
+
+func TestNewTimeCode(t *testing.T) {
+	tests := []struct {
+		frame int
+		rate  FrameRate
+		want  string
+	}{
+		{0, FrameRate24, "00:00:00:00"},
+		{23, FrameRate24, "00:00:00:23"},
+		{24, FrameRate24, "00:00:01:00"},
+		{90000, FrameRate25, "01:00:00:00"},
+		{108000 - 1, FrameRate30, "00:59:59:29"},
+		{0, FrameRate2997Drop, "00:00:00;00"},
+		{1799, FrameRate2997Drop, "00:00:59;29"},
+		// The frames 0 and 1 of every minute are skipped...
+		{1800, FrameRate2997Drop, "00:01:00;02"},
+		{3597, FrameRate2997Drop, "00:01:59;29"},
+		{3598, FrameRate2997Drop, "00:02:00;02"},
+		{17981, FrameRate2997Drop, "00:09:59;29"},
+		// ...but every tenth one.
+		{17982, FrameRate2997Drop, "00:10:00;00"},
+		{17983, FrameRate2997Drop, "00:10:00;01"},
+		{17982 + 1800, FrameRate2997Drop, "00:11:00;02"},
+		{6 * 17982, FrameRate2997Drop, "01:00:00;00"},
+	}
+	for _, test := range tests {
+		if got := NewTimeCode(test.frame, test.rate).String(); got != test.want {
+			t.Errorf("NewTimeCode(%d, %d) = %s, want %s", test.frame, test.rate, got, test.want)
+		}
+	}
+}
+
+func TestQuarterFrame(t *testing.T) {
+	tc := TimeCode{Hours: 17, Minutes: 42, Seconds: 31, Frames: 28, Rate: FrameRate2997Drop}
+	want := []uint8{0x0C, 0x11, 0x2F, 0x31, 0x4A, 0x52, 0x61, 0x75}
+	for piece, data := range want {
+		if got := tc.QuarterFrame(piece); got != data {
+			t.Errorf("QuarterFrame(%d) = %#02x, want %#02x", piece, got, data)
+		}
+	}
+}
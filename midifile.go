@@ -0,0 +1,41 @@
+package main
+
+import (
+	"log"
+
+	"sektron/filesystem"
+	"sektron/sequencer"
+)
+
+// runExport renders the given patterns, played one after the other, to a
+// Standard MIDI File.
+func runExport(seq sequencer.Sequencer, filename string, patterns []int, seed int64) {
+	seq.Lock()
+	defer seq.Unlock()
+	if err := seq.Export(filename, patterns, seed); err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("exported %s", filename)
+}
+
+// runImport reads the given Standard MIDI File into the given pattern.
+func runImport(seq sequencer.Sequencer, filename string, pattern int) {
+	seq.Lock()
+	defer seq.Unlock()
+	if err := seq.Import(filename, pattern); err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("imported %s to pattern %d", filename, pattern+1)
+}
+
+// songPatterns returns the indexes of all the used patterns of the bank, in
+// order.
+func songPatterns(bank filesystem.Bank) []int {
+	var patterns []int
+	for i, p := range bank.Patterns {
+		if !p.IsFree() {
+			patterns = append(patterns, i)
+		}
+	}
+	return patterns
+}
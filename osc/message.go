@@ -0,0 +1,184 @@
+package osc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// OSC 1.0 wire format: https://opensoundcontrol.stanford.edu/spec-1_0.html
+const bundleTag = "#bundle"
+
+var errMalformed = errors.New("malformed osc packet")
+
+// Message represents an OSC message: an address and its arguments.
+// Arguments can be int32, float32, string or bool values.
+type Message struct {
+	Address string
+	Args    []interface{}
+}
+
+// Int returns the argument at the given index as an int. Floats are rounded
+// and booleans are 0 or 1.
+func (m Message) Int(i int) (int, bool) {
+	value, ok := m.Float(i)
+	if !ok {
+		return 0, false
+	}
+	return int(math.Round(value)), true
+}
+
+// Float returns the argument at the given index as a float64.
+func (m Message) Float(i int) (float64, bool) {
+	if i >= len(m.Args) {
+		return 0, false
+	}
+	switch arg := m.Args[i].(type) {
+	case int32:
+		return float64(arg), true
+	case float32:
+		return float64(arg), true
+	case bool:
+		if arg {
+			return 1, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+// MarshalBinary encodes the message.
+func (m Message) MarshalBinary() ([]byte, error) {
+	var buf bytes.Buffer
+	writeString(&buf, m.Address)
+	tags := ","
+	var args bytes.Buffer
+	for _, arg := range m.Args {
+		switch arg := arg.(type) {
+		case int32:
+			tags += "i"
+			binary.Write(&args, binary.BigEndian, arg)
+		case float32:
+			tags += "f"
+			binary.Write(&args, binary.BigEndian, math.Float32bits(arg))
+		case string:
+			tags += "s"
+			writeString(&args, arg)
+		case bool:
+			if arg {
+				tags += "T"
+			} else {
+				tags += "F"
+			}
+		default:
+			return nil, fmt.Errorf("unsupported osc argument type %T", arg)
+		}
+	}
+	writeString(&buf, tags)
+	buf.Write(args.Bytes())
+	return buf.Bytes(), nil
+}
+
+// parsePacket decodes a packet holding either a message or a bundle. Bundle
+// time tags are ignored: messages are applied right away.
+func parsePacket(data []byte) ([]Message, error) {
+	if len(data) == 0 || len(data)%4 != 0 {
+		return nil, errMalformed
+	}
+	if data[0] != '#' {
+		msg, err := parseMessage(data)
+		if err != nil {
+			return nil, err
+		}
+		return []Message{msg}, nil
+	}
+
+	tag, data, err := readString(data)
+	if err != nil || tag != bundleTag || len(data) < 8 {
+		return nil, errMalformed
+	}
+	data = data[8:]
+	var messages []Message
+	for len(data) > 0 {
+		if len(data) < 4 {
+			return nil, errMalformed
+		}
+		size := int(binary.BigEndian.Uint32(data))
+		data = data[4:]
+		if size > len(data) {
+			return nil, errMalformed
+		}
+		elements, err := parsePacket(data[:size])
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, elements...)
+		data = data[size:]
+	}
+	return messages, nil
+}
+
+func parseMessage(data []byte) (Message, error) {
+	address, data, err := readString(data)
+	if err != nil || len(address) == 0 || address[0] != '/' {
+		return Message{}, errMalformed
+	}
+	msg := Message{Address: address}
+	// Some old implementations omit the type tags when there's no argument.
+	if len(data) == 0 {
+		return msg, nil
+	}
+	tags, data, err := readString(data)
+	if err != nil || len(tags) == 0 || tags[0] != ',' {
+		return Message{}, errMalformed
+	}
+	for _, tag := range tags[1:] {
+		switch tag {
+		case 'i', 'f':
+			if len(data) < 4 {
+				return Message{}, errMalformed
+			}
+			value := binary.BigEndian.Uint32(data)
+			data = data[4:]
+			if tag == 'i' {
+				msg.Args = append(msg.Args, int32(value))
+			} else {
+				msg.Args = append(msg.Args, math.Float32frombits(value))
+			}
+		case 's':
+			var value string
+			value, data, err = readString(data)
+			if err != nil {
+				return Message{}, err
+			}
+			msg.Args = append(msg.Args, value)
+		case 'T':
+			msg.Args = append(msg.Args, true)
+		case 'F':
+			msg.Args = append(msg.Args, false)
+		default:
+			return Message{}, fmt.Errorf("unsupported osc type tag %q", tag)
+		}
+	}
+	return msg, nil
+}
+
+// OSC strings are null terminated and padded to a multiple of 4 bytes.
+func writeString(buf *bytes.Buffer, value string) {
+	buf.WriteString(value)
+	buf.Write(make([]byte, 4-len(value)%4))
+}
+
+func readString(data []byte) (string, []byte, error) {
+	end := bytes.IndexByte(data, 0)
+	if end < 0 {
+		return "", nil, errMalformed
+	}
+	size := (end/4 + 1) * 4
+	if size > len(data) {
+		return "", nil, errMalformed
+	}
+	return string(data[:end]), data[size:], nil
+}
@@ -0,0 +1,99 @@
+package osc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"testing"
+)
+
+func TestWriteString(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"", "\x00\x00\x00\x00"},
+		{"/a", "/a\x00\x00"},
+		{"/ab", "/ab\x00"},
+		{"/abc", "/abc\x00\x00\x00\x00"},
+		{",iff", ",iff\x00\x00\x00\x00"},
+	}
+	for _, test := range tests {
+		var buf bytes.Buffer
+		writeString(&buf, test.value)
+		if got := buf.String(); got != test.want {
+			t.Errorf("writeString(%q) = %q, want %q", test.value, got, test.want)
+		}
+		value, rest, err := readString(buf.Bytes())
+		if err != nil || value != test.value || len(rest) != 0 {
+			t.Errorf("readString(%q) = %q, %q, %v", test.want, value, rest, err)
+		}
+	}
+}
+
+func TestMessageRoundTrip(t *testing.T) {
+	tests := []Message{
+		{Address: "/play"},
+		{Address: "/track/1/mute", Args: []interface{}{true}},
+		{Address: "/track/1/mute", Args: []interface{}{false}},
+		{Address: "/tempo", Args: []interface{}{float32(120.5)}},
+		{Address: "/note", Args: []interface{}{int32(1), int32(60), int32(100), float32(250)}},
+		{Address: "/name", Args: []interface{}{"bass", int32(-1), "lead"}},
+	}
+	for _, test := range tests {
+		data, err := test.MarshalBinary()
+		if err != nil {
+			t.Fatalf("%s: %v", test.Address, err)
+		}
+		if len(data)%4 != 0 {
+			t.Errorf("%s: %d bytes, not a multiple of 4", test.Address, len(data))
+		}
+		messages, err := parsePacket(data)
+		if err != nil {
+			t.Fatalf("%s: %v", test.Address, err)
+		}
+		if len(messages) != 1 || !reflect.DeepEqual(messages[0], test) {
+			t.Errorf("%s: got %+v, want %+v", test.Address, messages, test)
+		}
+	}
+}
+
+func TestParseBundle(t *testing.T) {
+	var buf bytes.Buffer
+	writeString(&buf, bundleTag)
+	buf.Write(make([]byte, 8))
+	for _, msg := range []Message{
+		{Address: "/play"},
+		{Address: "/tempo", Args: []interface{}{float32(90)}},
+	} {
+		data, _ := msg.MarshalBinary()
+		binary.Write(&buf, binary.BigEndian, uint32(len(data)))
+		buf.Write(data)
+	}
+
+	messages, err := parsePacket(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(messages) != 2 || messages[0].Address != "/play" || messages[1].Address != "/tempo" {
+		t.Errorf("got %+v", messages)
+	}
+}
+
+func TestParseMalformed(t *testing.T) {
+	tests := map[string][]byte{
+		"empty":             {},
+		"unpadded":          []byte("/a\x00"),
+		"no address":        []byte("abc\x00"),
+		"unterminated":      []byte("/abc"),
+		"missing int":       []byte("/a\x00\x00,i\x00\x00"),
+		"unknown tag":       []byte("/a\x00\x00,x\x00\x00"),
+		"truncated bundle":  append([]byte("#bundle\x00"), make([]byte, 4)...),
+		"oversized element": append(append([]byte("#bundle\x00"), make([]byte, 8)...), 0, 0, 0, 8, '/', 'a', 0, 0),
+	}
+	for name, data := range tests {
+		if _, err := parsePacket(data); err == nil {
+			t.Errorf("%s: no error", name)
+		}
+	}
+}
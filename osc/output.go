@@ -0,0 +1,133 @@
+package osc
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"time"
+
+	"sektron/filesystem"
+)
+
+// Output sends the track notes and controls to osc destinations, e.g.
+// SuperCollider, Pure Data or VCV Rack. It implements midi.Output: each
+// destination is a device, and the midi messages are translated to:
+//
+//	<prefix>/note channel note velocity length   (velocity 0 on note off)
+//	<prefix>/silence channel
+//	<prefix>/cc channel controller value
+//	<prefix>/program channel value
+//	<prefix>/pitchbend channel value
+//	<prefix>/aftertouch channel value
+//
+// Channels are numbered from 1 to 16, as in the ui. The note length is given
+// in milliseconds with the note on, at the current tempo, and by the time
+// between the note on and the note off, like a gate. It's 0 for the notes
+// that last until their note off.
+type Output struct {
+	conn         *net.UDPConn
+	destinations []destination
+}
+
+type destination struct {
+	name   string
+	addr   *net.UDPAddr
+	prefix string
+}
+
+// NewOutput creates an Output for the given destinations.
+func NewOutput(outputs []filesystem.OSCOutput) (*Output, error) {
+	o := &Output{}
+	for _, output := range outputs {
+		addr, err := net.ResolveUDPAddr("udp", output.Address)
+		if err != nil {
+			return nil, fmt.Errorf("invalid osc output %q: %w", output.Name, err)
+		}
+		o.destinations = append(o.destinations, destination{
+			name:   output.Name,
+			addr:   addr,
+			prefix: output.Prefix,
+		})
+	}
+	conn, err := net.ListenUDP("udp", nil)
+	if err != nil {
+		return nil, err
+	}
+	o.conn = conn
+	return o, nil
+}
+
+// Close closes the output socket.
+func (o *Output) Close() error {
+	return o.conn.Close()
+}
+
+// Names returns the destination names.
+func (o *Output) Names() []string {
+	var names []string
+	for _, d := range o.destinations {
+		names = append(names, d.name)
+	}
+	return names
+}
+
+// NoteOn sends a note message without length to the given destination.
+func (o *Output) NoteOn(device int, channel, note, velocity uint8) {
+	o.NoteOnFor(device, channel, note, velocity, 0)
+}
+
+// NoteOnFor sends a note message with its length to the given destination.
+func (o *Output) NoteOnFor(device int, channel, note, velocity uint8, length time.Duration) {
+	ms := float32(length) / float32(time.Millisecond)
+	o.send(device, "/note", channel, int32(note), int32(velocity), ms)
+}
+
+// NoteOff sends a note message with a 0 velocity to the given destination.
+func (o *Output) NoteOff(device int, channel, note uint8) {
+	o.send(device, "/note", channel, int32(note), int32(0), float32(0))
+}
+
+// Silence sends a silence message to the given destination, so that it
+// stops all the playing notes of the channel.
+func (o *Output) Silence(device int, channel uint8) {
+	o.send(device, "/silence", channel)
+}
+
+// ControlChange sends a cc message to the given destination.
+func (o *Output) ControlChange(device int, channel, controller, value uint8) {
+	o.send(device, "/cc", channel, int32(controller), int32(value))
+}
+
+// ProgramChange sends a program message to the given destination.
+func (o *Output) ProgramChange(device int, channel, value uint8) {
+	o.send(device, "/program", channel, int32(value))
+}
+
+// Pitchbend sends a pitchbend message to the given destination.
+func (o *Output) Pitchbend(device int, channel uint8, value int16) {
+	o.send(device, "/pitchbend", channel, int32(value))
+}
+
+// AfterTouch sends an aftertouch message to the given destination.
+func (o *Output) AfterTouch(device int, channel, value uint8) {
+	o.send(device, "/aftertouch", channel, int32(value))
+}
+
+func (o *Output) send(device int, address string, channel uint8, args ...interface{}) {
+	if device < 0 || device >= len(o.destinations) {
+		return
+	}
+	d := o.destinations[device]
+	msg := Message{
+		Address: d.prefix + address,
+		Args:    append([]interface{}{int32(channel) + 1}, args...),
+	}
+	packet, err := msg.MarshalBinary()
+	if err != nil {
+		log.Printf("osc: %s", err)
+		return
+	}
+	if _, err := o.conn.WriteToUDP(packet, d.addr); err != nil {
+		log.Printf("osc: %s: %s", d.name, err)
+	}
+}
@@ -0,0 +1,207 @@
+package osc
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"sektron/sequencer"
+)
+
+// Addresses:
+//
+//	/sektron/register [port]                  receive the state feedback, on the sender port by default
+//	/sektron/unregister [port]                stop receiving the state feedback
+//	/sektron/play, /sektron/stop              start or stop playing
+//	/sektron/toggleplay                       start or stop playing
+//	/sektron/tempo f                          set the tempo
+//	/sektron/load i                           switch to a pattern (at the end of the current one when playing)
+//	/sektron/chain i                          chain a pattern
+//	/sektron/track/T/toggle                   mute or unmute a track
+//	/sektron/track/T/device i                 set a track device
+//	/sektron/track/T/channel i                set a track channel
+//	/sektron/track/T/step/S/toggle            activate or deactivate a step
+//	/sektron/track/T/step/S/offset i          set a step offset
+//	/sektron/track/T[/step/S]/note i...       set a track or step note (or chord)
+//	/sektron/track/T[/step/S]/length i        set a track or step length, in pulses
+//	/sektron/track/T[/step/S]/velocity i      set a track or step velocity
+//	/sektron/track/T[/step/S]/probability i   set a track or step probability
+//	/sektron/track/T[/step/S]/control/C i     set a track or step midi control
+//
+// Buttons usually send 1 when pressed and 0 when released: actions without
+// a value are only applied when the first argument, if any, isn't 0.
+//
+// The state feedback is sent on /sektron/playing, /sektron/tempo,
+// /sektron/pattern, /sektron/track/T/active and /sektron/track/T/step.
+
+var errMissingArgument = errors.New("missing argument")
+
+func (s *Server) dispatch(msg Message, from *net.UDPAddr) error {
+	if !strings.HasPrefix(msg.Address, prefix+"/") {
+		return errors.New("unknown address")
+	}
+	parts := strings.Split(strings.TrimPrefix(msg.Address, prefix+"/"), "/")
+
+	switch parts[0] {
+	case "register", "unregister":
+		addr := &net.UDPAddr{IP: from.IP, Port: from.Port, Zone: from.Zone}
+		if port, ok := msg.Int(0); ok {
+			addr.Port = port
+		}
+		if parts[0] == "register" {
+			s.register(addr)
+		} else {
+			s.unregister(addr)
+		}
+	case "play":
+		if pressed(msg) && !s.seq.IsPlaying() {
+			s.seq.TogglePlay()
+		}
+	case "stop":
+		if pressed(msg) && s.seq.IsPlaying() {
+			s.seq.TogglePlay()
+		}
+	case "toggleplay":
+		if pressed(msg) {
+			s.seq.TogglePlay()
+		}
+	case "tempo":
+		tempo, ok := msg.Float(0)
+		if !ok {
+			return errMissingArgument
+		}
+		s.seq.SetTempo(tempo)
+	case "load", "chain":
+		pattern, ok := msg.Int(0)
+		if !ok {
+			return errMissingArgument
+		}
+		if pattern < 0 || pattern >= len(s.seq.Patterns()) {
+			return fmt.Errorf("invalid pattern %d", pattern)
+		}
+		switch {
+		case parts[0] == "chain":
+			s.seq.Chain(pattern)
+		case s.seq.IsPlaying():
+			s.seq.ChainNow(pattern)
+		default:
+			s.seq.Save()
+			s.seq.Load(pattern)
+		}
+	case "track":
+		return s.dispatchTrack(parts[1:], msg)
+	default:
+		return errors.New("unknown address")
+	}
+	return nil
+}
+
+func (s *Server) dispatchTrack(parts []string, msg Message) error {
+	tracks := s.seq.Tracks()
+	if len(parts) < 2 {
+		return errors.New("unknown address")
+	}
+	index, err := strconv.Atoi(parts[0])
+	if err != nil || index < 0 || index >= len(tracks) {
+		return fmt.Errorf("invalid track %s", parts[0])
+	}
+	track := tracks[index]
+
+	switch parts[1] {
+	case "toggle":
+		if pressed(msg) {
+			s.seq.ToggleTrack(index)
+		}
+	case "device":
+		device, ok := msg.Int(0)
+		if !ok {
+			return errMissingArgument
+		}
+		track.SetDevice(device)
+	case "channel":
+		channel, ok := msg.Int(0)
+		if !ok {
+			return errMissingArgument
+		}
+		if channel < 0 || channel > 15 {
+			return fmt.Errorf("invalid channel %d", channel)
+		}
+		track.SetChannel(uint8(channel))
+	case "step":
+		if len(parts) < 4 {
+			return errors.New("unknown address")
+		}
+		steps := track.Steps()
+		position, err := strconv.Atoi(parts[2])
+		if err != nil || position < 0 || position >= len(steps) {
+			return fmt.Errorf("invalid step %s", parts[2])
+		}
+		step := steps[position]
+		switch parts[3] {
+		case "toggle":
+			if pressed(msg) {
+				s.seq.ToggleStep(index, position)
+			}
+		case "offset":
+			offset, ok := msg.Int(0)
+			if !ok {
+				return errMissingArgument
+			}
+			step.SetOffset(offset)
+		default:
+			return setParameter(step, len(track.Controls()), parts[3:], msg)
+		}
+	default:
+		return setParameter(track, len(track.Controls()), parts[1:], msg)
+	}
+	return nil
+}
+
+// setParameter sets a track or step parameter.
+func setParameter(item sequencer.Parametrable, controls int, parts []string, msg Message) error {
+	value, ok := msg.Int(0)
+	if !ok {
+		return errMissingArgument
+	}
+	switch parts[0] {
+	case "note":
+		var chord []uint8
+		for i := range msg.Args {
+			note, ok := msg.Int(i)
+			if !ok || note < 0 || note > 127 {
+				return fmt.Errorf("invalid note %v", msg.Args[i])
+			}
+			chord = append(chord, uint8(note))
+		}
+		item.SetChord(chord)
+	case "length":
+		item.SetLength(value)
+	case "velocity":
+		if value < 0 || value > 127 {
+			return fmt.Errorf("invalid velocity %d", value)
+		}
+		item.SetVelocity(uint8(value))
+	case "probability":
+		item.SetProbability(value)
+	case "control":
+		if len(parts) < 2 {
+			return errors.New("unknown address")
+		}
+		control, err := strconv.Atoi(parts[1])
+		if err != nil || control < 0 || control >= controls {
+			return fmt.Errorf("invalid control %s", parts[1])
+		}
+		item.SetControl(control, int16(value))
+	default:
+		return errors.New("unknown address")
+	}
+	return nil
+}
+
+// pressed returns false for the button release messages.
+func pressed(msg Message) bool {
+	value, ok := msg.Float(0)
+	return !ok || value != 0
+}
@@ -0,0 +1,185 @@
+// Package osc implements an Open Sound Control server, so that TouchOSC
+// layouts or scripts can control the sequencer over UDP.
+//
+// Addresses map onto the Sequencer, Track and Step methods (check
+// routes.go). Patterns, tracks and steps are numbered from 0, as in the
+// Sequencer interface. The server sends the sequencer state back to the
+// registered clients each time it changes.
+package osc
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"sync"
+	"time"
+
+	"sektron/sequencer"
+)
+
+const (
+	prefix = "/sektron"
+
+	// feedbackFrequency is how often the sequencer state is checked for
+	// changes to send to the clients. It's short enough to follow the
+	// current steps at high tempos.
+	feedbackFrequency = 10 * time.Millisecond
+
+	maxPacketSize = 64 * 1024
+)
+
+// Server receives OSC messages on a UDP port and applies them to the
+// sequencer.
+type Server struct {
+	seq  sequencer.Sequencer
+	conn *net.UDPConn
+	done chan struct{}
+
+	// clients holds the addresses that receive the state feedback, and sent
+	// the last state values sent to them, by address.
+	mu      sync.Mutex
+	clients map[string]*net.UDPAddr
+	sent    map[string]string
+}
+
+// Listen starts an OSC server on the given UDP port. The state feedback is
+// sent to the given clients (host:port addresses), along with the ones that
+// register at runtime.
+func Listen(port int, clients []string, seq sequencer.Sequencer) (*Server, error) {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{Port: port})
+	if err != nil {
+		return nil, err
+	}
+	s := &Server{
+		seq:     seq,
+		conn:    conn,
+		done:    make(chan struct{}),
+		clients: map[string]*net.UDPAddr{},
+		sent:    map[string]string{},
+	}
+	for _, client := range clients {
+		addr, err := net.ResolveUDPAddr("udp", client)
+		if err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("invalid osc client %q: %w", client, err)
+		}
+		s.clients[addr.String()] = addr
+	}
+	go s.receive()
+	go s.sendFeedback()
+	return s, nil
+}
+
+// Close stops the server.
+func (s *Server) Close() error {
+	close(s.done)
+	return s.conn.Close()
+}
+
+func (s *Server) receive() {
+	buf := make([]byte, maxPacketSize)
+	for {
+		n, from, err := s.conn.ReadFromUDP(buf)
+		if err != nil {
+			select {
+			case <-s.done:
+				return
+			default:
+				log.Printf("osc: %s", err)
+				continue
+			}
+		}
+		messages, err := parsePacket(buf[:n])
+		if err != nil {
+			log.Printf("osc: %s from %s", err, from)
+			continue
+		}
+		// The messages are applied while holding the sequencer lock, as the
+		// tracks also update their parameters (check sequencer.Sequencer).
+		s.seq.Lock()
+		for _, msg := range messages {
+			if err := s.dispatch(msg, from); err != nil {
+				log.Printf("osc: %s: %s", msg.Address, err)
+			}
+		}
+		s.seq.Unlock()
+	}
+}
+
+// register adds a client to the feedback list and sends it the whole state.
+// It's called while dispatching, with the sequencer lock held.
+func (s *Server) register(addr *net.UDPAddr) {
+	s.mu.Lock()
+	s.clients[addr.String()] = addr
+	s.mu.Unlock()
+	for _, msg := range s.state() {
+		s.send(addr, msg)
+	}
+}
+
+func (s *Server) unregister(addr *net.UDPAddr) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.clients, addr.String())
+}
+
+func (s *Server) sendFeedback() {
+	ticker := time.NewTicker(feedbackFrequency)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+		}
+
+		s.seq.Lock()
+		state := s.state()
+		s.seq.Unlock()
+
+		var changed []Message
+		for _, msg := range state {
+			value := fmt.Sprint(msg.Args...)
+			if s.sent[msg.Address] != value {
+				s.sent[msg.Address] = value
+				changed = append(changed, msg)
+			}
+		}
+		if len(changed) == 0 {
+			continue
+		}
+		s.mu.Lock()
+		for _, addr := range s.clients {
+			for _, msg := range changed {
+				s.send(addr, msg)
+			}
+		}
+		s.mu.Unlock()
+	}
+}
+
+func (s *Server) send(addr *net.UDPAddr, msg Message) {
+	packet, err := msg.MarshalBinary()
+	if err != nil {
+		log.Printf("osc: %s", err)
+		return
+	}
+	s.conn.WriteToUDP(packet, addr)
+}
+
+// state returns the feedback messages describing the sequencer state. The
+// sequencer lock must be held.
+func (s *Server) state() []Message {
+	messages := []Message{
+		{Address: prefix + "/playing", Args: []interface{}{s.seq.IsPlaying()}},
+		{Address: prefix + "/tempo", Args: []interface{}{float32(s.seq.Tempo())}},
+		{Address: prefix + "/pattern", Args: []interface{}{int32(s.seq.ActivePattern())}},
+	}
+	for i, track := range s.seq.Tracks() {
+		messages = append(messages,
+			Message{Address: fmt.Sprintf("%s/track/%d/active", prefix, i), Args: []interface{}{track.IsActive()}},
+			Message{Address: fmt.Sprintf("%s/track/%d/step", prefix, i), Args: []interface{}{int32(track.CurrentStep())}},
+		)
+	}
+	return messages
+}
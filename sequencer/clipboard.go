@@ -0,0 +1,146 @@
+package sequencer
+
+import (
+	"fmt"
+
+	"sektron/filesystem"
+)
+
+// clipboard holds copied content for the different scopes (range of steps,
+// track, pattern). It's stored in its serializable form, so that it can be
+// pasted into any track or bank slot, even after the original one has been
+// modified.
+type clipboard struct {
+	steps   []filesystem.Step
+	track   *filesystem.Track
+	pattern *filesystem.Pattern
+}
+
+// CopySteps copies a range of steps of the given track, first and last steps
+// included.
+func (s *sequencer) CopySteps(track, first, last int) {
+	if !s.isValidStepRange(track, first, last) {
+		return
+	}
+	s.clipboard.steps = nil
+	for _, stp := range s.tracks[track].steps[first : last+1] {
+		s.clipboard.steps = append(s.clipboard.steps, copyStep(stp.serialize()))
+	}
+}
+
+// PasteSteps pastes the copied steps into the given track, starting from the
+// given step. Copied steps that would go beyond the last step of the track are
+// ignored.
+func (s *sequencer) PasteSteps(track, first int) {
+	if len(s.clipboard.steps) == 0 || !s.isValidStepRange(track, first, first) {
+		return
+	}
+	s.record(fmt.Sprintf("paste %d steps into track %d", len(s.clipboard.steps), track+1))
+	t := s.tracks[track]
+	for i, stp := range s.clipboard.steps {
+		position := first + i
+		if position >= len(t.steps) {
+			break
+		}
+		t.steps[position].reset()
+		t.steps[position] = newStep(t, position, copyStep(stp))
+	}
+}
+
+// ClearSteps desactivates a range of steps of the given track, first and last
+// steps included, and removes all their parameters.
+func (s *sequencer) ClearSteps(track, first, last int) {
+	if !s.isValidStepRange(track, first, last) {
+		return
+	}
+	s.record(fmt.Sprintf("clear steps %d-%d of track %d", first+1, last+1, track+1))
+	s.clearSteps(track, first, last)
+}
+
+func (s *sequencer) clearSteps(track, first, last int) {
+	for _, stp := range s.tracks[track].steps[first : last+1] {
+		stp.clearParameters()
+		stp.active = false
+	}
+}
+
+// CopyTrack copies a whole track: steps, parameters, controls, device and
+// channel.
+func (s *sequencer) CopyTrack(track int) {
+	if track < 0 || track >= len(s.tracks) {
+		return
+	}
+	t := copyTrack(s.tracks[track].serialize())
+	s.clipboard.track = &t
+}
+
+// PasteTrack replaces the given track with the copied one.
+func (s *sequencer) PasteTrack(track int) {
+	if s.clipboard.track == nil || track < 0 || track >= len(s.tracks) {
+		return
+	}
+	s.record(fmt.Sprintf("paste track %d", track+1))
+	s.tracks[track].clear()
+	s.tracks[track].load(copyTrack(*s.clipboard.track))
+}
+
+// ClearTrack desactivates all the steps of the given track and removes their
+// parameters. Track parameters are left untouched.
+func (s *sequencer) ClearTrack(track int) {
+	if track < 0 || track >= len(s.tracks) {
+		return
+	}
+	s.record(fmt.Sprintf("clear track %d", track+1))
+	s.clearSteps(track, 0, len(s.tracks[track].steps)-1)
+}
+
+// CopyPattern copies a whole pattern from the bank. The active pattern is
+// copied in its current state.
+func (s *sequencer) CopyPattern(pattern int) {
+	if pattern < 0 || pattern >= len(s.bank.Patterns) {
+		return
+	}
+	var p filesystem.Pattern
+	if pattern == s.bank.Active {
+		p = copyPattern(s.pattern())
+	} else {
+		p = copyPattern(s.bank.Patterns[pattern])
+	}
+	s.clipboard.pattern = &p
+}
+
+// PastePattern replaces the given bank slot with the copied pattern. If it's
+// the active pattern, the tracks are replaced right away but the running
+// tempo is kept.
+func (s *sequencer) PastePattern(pattern int) {
+	if s.clipboard.pattern == nil || pattern < 0 || pattern >= len(s.bank.Patterns) {
+		return
+	}
+	s.record(fmt.Sprintf("paste pattern %d", pattern+1), pattern)
+	s.replacePattern(pattern, copyPattern(*s.clipboard.pattern))
+}
+
+// ClearPattern frees the given bank slot. If it's the active pattern, the
+// tracks are replaced by default ones.
+func (s *sequencer) ClearPattern(pattern int) {
+	if pattern < 0 || pattern >= len(s.bank.Patterns) {
+		return
+	}
+	s.record(fmt.Sprintf("clear pattern %d", pattern+1), pattern)
+	s.replacePattern(pattern, filesystem.Pattern{})
+}
+
+func (s *sequencer) replacePattern(pattern int, p filesystem.Pattern) {
+	s.bank.Patterns[pattern] = p
+	if pattern == s.bank.Active {
+		s.reload(p)
+	}
+	s.bank.Save()
+}
+
+func (s *sequencer) isValidStepRange(track, first, last int) bool {
+	if track < 0 || track >= len(s.tracks) {
+		return false
+	}
+	return first >= 0 && first <= last && last < len(s.tracks[track].steps)
+}
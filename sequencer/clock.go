@@ -1,6 +1,10 @@
 package sequencer
 
-import "time"
+import (
+	"math"
+	"sync"
+	"time"
+)
 
 const (
 	pulsesPerStep       int     = 6
@@ -8,55 +12,280 @@ const (
 	tempoMin            float64 = 1.0
 	tempoMax            float64 = 300.0
 	updateBufferSize    int     = 128
+	pulsesPerBar        int     = pulsesPerStep * stepsPerQuarterNote * 4
+
+	// When nudged, the clock runs faster or slower by this ratio for a short
+	// period of time. It allows to beat-match with another musician without
+	// changing the tempo.
+	nudgeRatio    float64       = 0.04
+	nudgeDuration time.Duration = 200 * time.Millisecond
+
+	// If the clock is late by more than this duration (e.g. the system was
+	// suspended), we don't try to catch up with the missed pulses and start
+	// over from now.
+	maxLateness time.Duration = 100 * time.Millisecond
 )
 
 // clock contains a clock state.
-// We use the standard time.ticker as the sequencer clock, ticking at the
-// standard midi 6 pulses per 16th note (one step).
-// The update chan is used to pass new tempo values and recreate a new ticker.
+// The clock ticks at the standard midi 6 pulses per 16th note (one step).
+// Instead of using a time.Ticker, each pulse is scheduled at an absolute
+// time computed from the time of the first pulse and the tempo. This way,
+// rounding errors and timer jitter don't accumulate over time: a late pulse
+// makes the next one come sooner.
+// The update chan is used to pass new tempo values or tempo ramps.
+// The nudge chan is used to temporarily speed up or slow down the clock.
 //
 // Read more: http://midi.teragonaudio.com/tech/midispec/clock.htm
 type clock struct {
-	ticker       *time.Ticker
-	update       chan float64
+	timer        *time.Timer
+	update       chan tempoRamp
+	nudge        chan float64
+	align        chan alignment
 	tempo        float64
 	shouldUpdate bool
+
+	// start holds the scheduled time of the first pulse since the last tempo
+	// change, and pulse the number of pulses since then. scheduled holds the
+	// time the timer is set to.
+	start     time.Time
+	pulse     int64
+	scheduled time.Time
+
+	// While ramping, the tempo is recalculated at each pulse.
+	ramp *tempoRamp
+
+	// While nudged, the clock runs at tempo * nudgeFactor until nudgeEnd.
+	nudgeFactor float64
+	nudgeEnd    time.Time
+
+	stats jitterStats
+}
+
+// tempoRamp represents a linear tempo change over a number of pulses. A
+// ramp over 0 pulses is an immediate tempo change.
+type tempoRamp struct {
+	from   float64
+	to     float64
+	pulses int
+	pulse  int
+}
+
+// tempoAt returns the ramp tempo at its current pulse.
+func (r tempoRamp) tempoAt() float64 {
+	if r.pulse >= r.pulses {
+		return r.to
+	}
+	return r.from + (r.to-r.from)*float64(r.pulse)/float64(r.pulses)
+}
+
+// alignment sets the clock tempo and schedules the next pulse at a given
+// time, e.g. to follow an Ableton Link session beat grid.
+type alignment struct {
+	tempo float64
+	next  time.Time
 }
 
+// ClockStats holds the clock timing statistics: how late pulses were sent
+// compared to their scheduled time.
+type ClockStats struct {
+	Pulses    int64
+	Mean      time.Duration
+	Max       time.Duration
+	Deviation time.Duration
+}
+
+// jitterStats computes the pulses lateness mean and standard deviation on
+// the fly, using Welford's algorithm.
+// Stats are written by the clock goroutine and read by the ui, hence the
+// mutex.
+type jitterStats struct {
+	mu    sync.Mutex
+	count int64
+	mean  float64
+	m2    float64
+	max   float64
+}
+
+func (j *jitterStats) add(lateness time.Duration) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	value := float64(lateness)
+	j.count++
+	delta := value - j.mean
+	j.mean += delta / float64(j.count)
+	j.m2 += delta * (value - j.mean)
+	j.max = math.Max(j.max, value)
+}
+
+func (j *jitterStats) get() ClockStats {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	stats := ClockStats{
+		Pulses: j.count,
+		Mean:   time.Duration(j.mean),
+		Max:    time.Duration(j.max),
+	}
+	if j.count > 1 {
+		stats.Deviation = time.Duration(math.Sqrt(j.m2 / float64(j.count-1)))
+	}
+	return stats
+}
+
+// setTempo sets a new tempo, stopping any running ramp.
 func (c *clock) setTempo(tempo float64) {
 	if tempo > tempoMax || tempo < tempoMin {
 		return
 	}
-	c.update <- tempo
+	c.update <- tempoRamp{from: tempo, to: tempo}
 }
 
-func newClock(tempo float64, tick func()) *clock {
+// setRamp sets the tempo to the from value and makes it progressively reach
+// the to value over the given number of pulses.
+func (c *clock) setRamp(from, to float64, pulses int) {
+	if from > tempoMax || from < tempoMin || to > tempoMax || to < tempoMin {
+		return
+	}
+	c.update <- tempoRamp{from: from, to: to, pulses: pulses}
+}
+
+// rampTarget returns the tempo the clock is ramping to, if ramping.
+func (c *clock) rampTarget() (float64, bool) {
+	ramp := c.ramp
+	if ramp == nil {
+		return 0, false
+	}
+	return ramp.to, true
+}
+
+// alignTo sets the tempo and schedules the next pulse at the given time,
+// stopping any running ramp or nudge.
+func (c *clock) alignTo(tempo float64, next time.Time) {
+	if tempo > tempoMax || tempo < tempoMin {
+		return
+	}
+	c.align <- alignment{tempo: tempo, next: next}
+}
+
+// setNudge speeds up (positive direction) or slows down (negative direction)
+// the clock for a short period of time. Each call extends the period.
+func (c *clock) setNudge(direction int) {
+	switch {
+	case direction > 0:
+		c.nudge <- 1 + nudgeRatio
+	case direction < 0:
+		c.nudge <- 1 - nudgeRatio
+	}
+}
+
+// newClock starts a clock calling tick at each pulse. The given lock is held
+// while handling the pulses and the updates.
+func newClock(tempo float64, lock sync.Locker, tick func()) *clock {
 	c := &clock{
-		ticker: time.NewTicker(newClockInterval(tempo)),
-		update: make(chan float64, updateBufferSize),
-		tempo:  tempo,
+		update:      make(chan tempoRamp, updateBufferSize),
+		nudge:       make(chan float64, updateBufferSize),
+		align:       make(chan alignment, updateBufferSize),
+		tempo:       tempo,
+		nudgeFactor: 1,
+		start:       time.Now(),
 	}
+	c.scheduled = c.next()
+	c.timer = time.NewTimer(time.Until(c.scheduled))
 	go func(c *clock) {
 		for {
 			select {
-			case <-c.ticker.C:
+			case <-c.timer.C:
+				lock.Lock()
+				c.stats.add(time.Since(c.scheduled))
 				tick()
-				if c.shouldUpdate {
-					c.ticker.Reset(newClockInterval(c.tempo))
-					c.shouldUpdate = false
+				if c.ramp != nil {
+					c.advanceRamp()
+				}
+				if c.nudgeFactor != 1 && time.Now().After(c.nudgeEnd) {
+					c.nudgeFactor = 1
+					c.shouldUpdate = true
 				}
-			case newTempo := <-c.update:
+				c.schedule()
+				lock.Unlock()
+			case ramp := <-c.update:
+				lock.Lock()
 				// we wait for the next tick to update in order
 				// to prevent jitter
 				c.shouldUpdate = true
-				c.tempo = newTempo
+				c.tempo = ramp.from
+				c.ramp = nil
+				if ramp.pulses > 0 {
+					c.ramp = &ramp
+				}
+				lock.Unlock()
+			case factor := <-c.nudge:
+				lock.Lock()
+				if c.nudgeFactor != factor {
+					c.shouldUpdate = true
+				}
+				c.nudgeFactor = factor
+				c.nudgeEnd = time.Now().Add(nudgeDuration)
+				lock.Unlock()
+			case a := <-c.align:
+				lock.Lock()
+				c.tempo = a.tempo
+				c.ramp = nil
+				c.nudgeFactor = 1
+				c.shouldUpdate = false
+				c.start = a.next.Add(-time.Duration(c.interval()))
+				c.pulse = 0
+				c.scheduled = a.next
+				if !c.timer.Stop() {
+					select {
+					case <-c.timer.C:
+					default:
+					}
+				}
+				c.timer.Reset(time.Until(c.scheduled))
+				lock.Unlock()
 			}
 		}
 	}(c)
 	return c
 }
 
-func newClockInterval(tempo float64) time.Duration {
-	// midi clock: http://midi.teragonaudio.com/tech/midispec/clock.htm
-	return time.Duration(1000000*60/(tempo*float64(pulsesPerStep*stepsPerQuarterNote))) * time.Microsecond
+// next returns the scheduled time of the next pulse.
+func (c *clock) next() time.Time {
+	return c.start.Add(time.Duration(float64(c.pulse+1) * c.interval()))
+}
+
+// interval returns the duration between two pulses in nanoseconds.
+// midi clock: http://midi.teragonaudio.com/tech/midispec/clock.htm
+func (c *clock) interval() float64 {
+	return float64(time.Minute) / (c.tempo * c.nudgeFactor * float64(pulsesPerStep*stepsPerQuarterNote))
+}
+
+// schedule sets the timer for the next pulse, once the current one has been
+// sent. On tempo change, we start counting pulses again from the current
+// pulse scheduled time, as the tempo may have changed since then.
+func (c *clock) schedule() {
+	if c.shouldUpdate {
+		c.start = c.scheduled
+		c.pulse = 0
+		c.shouldUpdate = false
+	} else {
+		c.pulse++
+	}
+
+	if time.Since(c.next()) > maxLateness {
+		c.start = time.Now()
+		c.pulse = 0
+	}
+	c.scheduled = c.next()
+	c.timer.Reset(time.Until(c.scheduled))
+}
+
+// advanceRamp moves the ramp forward by one pulse and recalculates the tempo.
+// The next pulse is then scheduled with the new tempo.
+func (c *clock) advanceRamp() {
+	c.ramp.pulse++
+	c.tempo = c.ramp.tempoAt()
+	c.shouldUpdate = true
+	if c.ramp.pulse >= c.ramp.pulses {
+		c.ramp = nil
+	}
 }
@@ -1,5 +1,7 @@
 package sequencer
 
+import "sektron/filesystem"
+
 // Helper functions for deep copying pointers
 func copyIntPtr(ptr *int) *int {
 	if ptr == nil {
@@ -25,3 +27,71 @@ func copyUint8SlicePtr(ptr *[]uint8) *[]uint8 {
 	copy(c, *ptr)
 	return &c
 }
+
+func copyControls(controls map[int]int16) map[int]int16 {
+	if controls == nil {
+		return nil
+	}
+	c := make(map[int]int16, len(controls))
+	for k, v := range controls {
+		c[k] = v
+	}
+	return c
+}
+
+// copyStep returns a deep copy of the given step.
+func copyStep(step filesystem.Step) filesystem.Step {
+	return filesystem.Step{
+		Active:      step.Active,
+		Controls:    copyControls(step.Controls),
+		Length:      copyIntPtr(step.Length),
+		Chord:       copyUint8SlicePtr(step.Chord),
+		Velocity:    copyUint8Ptr(step.Velocity),
+		Probability: copyIntPtr(step.Probability),
+		Offset:      step.Offset,
+	}
+}
+
+// copyTrack returns a deep copy of the given track.
+func copyTrack(track filesystem.Track) filesystem.Track {
+	steps := make([]filesystem.Step, len(track.Steps))
+	for i, s := range track.Steps {
+		steps[i] = copyStep(s)
+	}
+	return filesystem.Track{
+		Steps:       steps,
+		Device:      track.Device,
+		Channel:     track.Channel,
+		Controls:    copyControls(track.Controls),
+		Length:      track.Length,
+		Chord:       append([]uint8{}, track.Chord...),
+		Velocity:    track.Velocity,
+		Probability: track.Probability,
+	}
+}
+
+// copyPattern returns a deep copy of the given pattern.
+func copyPattern(pattern filesystem.Pattern) filesystem.Pattern {
+	var ramp *filesystem.TempoRamp
+	if pattern.Ramp != nil {
+		r := *pattern.Ramp
+		ramp = &r
+	}
+	if pattern.Tracks == nil {
+		return filesystem.Pattern{Tempo: pattern.Tempo, Ramp: ramp}
+	}
+	tracks := make([]filesystem.Track, len(pattern.Tracks))
+	for i, t := range pattern.Tracks {
+		tracks[i] = copyTrack(t)
+	}
+	return filesystem.Pattern{
+		Tempo:  pattern.Tempo,
+		Tracks: tracks,
+		Ramp:   ramp,
+	}
+}
+
+// mod returns the euclidean modulo, always positive.
+func mod(a, b int) int {
+	return (a%b + b) % b
+}
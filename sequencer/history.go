@@ -0,0 +1,181 @@
+package sequencer
+
+import "sektron/filesystem"
+
+const (
+	// historySize bounds the number of undo and redo entries kept in memory.
+	// Each entry holds a full copy of the patterns it affects.
+	historySize int = 100
+)
+
+// snapshot holds the state of one or more bank patterns before an edit, so
+// that it can be restored later on.
+type snapshot struct {
+	description string
+
+	// patterns holds the pattern states, indexed by bank slot. The active
+	// pattern state is taken from the running tracks, not from the bank.
+	patterns map[int]filesystem.Pattern
+
+	// Track activation isn't part of the pattern file, so we keep it aside
+	// for the active pattern.
+	active []bool
+
+	// Successive parameter edits with the same description are merged into
+	// a single entry. Otherwise, pressing up 20 times on the velocity would
+	// require 20 undos.
+	coalesce bool
+}
+
+// history holds the undo and redo stacks.
+type history struct {
+	undo []snapshot
+	redo []snapshot
+
+	// While grouping, edits are not recorded individually (check Edit).
+	grouping bool
+}
+
+// push adds a snapshot on top of the given stack, dropping the oldest one if
+// the stack is full.
+func push(stack []snapshot, snap snapshot) []snapshot {
+	if len(stack) >= historySize {
+		stack = stack[len(stack)-historySize+1:]
+	}
+	return append(stack, snap)
+}
+
+// pop removes the snapshot on top of the given stack.
+func pop(stack []snapshot) (snapshot, []snapshot) {
+	return stack[len(stack)-1], stack[:len(stack)-1]
+}
+
+// Undo reverts the last edit. It returns the description of the reverted
+// edit, or an empty string if there was nothing to undo.
+func (s *sequencer) Undo() string {
+	if len(s.history.undo) == 0 {
+		return ""
+	}
+	var snap snapshot
+	snap, s.history.undo = pop(s.history.undo)
+	s.history.redo = push(s.history.redo, s.restore(snap))
+	return snap.description
+}
+
+// Redo applies the last reverted edit again. It returns the description of
+// the edit, or an empty string if there was nothing to redo.
+func (s *sequencer) Redo() string {
+	if len(s.history.redo) == 0 {
+		return ""
+	}
+	var snap snapshot
+	snap, s.history.redo = pop(s.history.redo)
+	s.history.undo = push(s.history.undo, s.restore(snap))
+	return snap.description
+}
+
+// Edit runs the given function as one single history entry, whatever the
+// number of edits it makes. Like parameter edits, successive calls with the
+// same description are merged.
+func (s *sequencer) Edit(description string, edit func()) {
+	s.recordEdit(description)
+	s.history.grouping = true
+	defer func() {
+		s.history.grouping = false
+	}()
+	edit()
+}
+
+// record saves the state of the given bank slots (or the active pattern if
+// none given) before an edit happens. It must be called before applying the
+// edit.
+func (s *sequencer) record(description string, patterns ...int) {
+	if s.history.grouping {
+		return
+	}
+	s.history.undo = push(s.history.undo, s.capture(description, patterns...))
+	s.history.redo = nil
+}
+
+// recordEdit works like record, but merges successive edits with the same
+// description in one single history entry.
+func (s *sequencer) recordEdit(description string) {
+	if s.history.grouping {
+		return
+	}
+	if len(s.history.undo) > 0 && len(s.history.redo) == 0 {
+		last := s.history.undo[len(s.history.undo)-1]
+		if last.coalesce && last.description == description {
+			return
+		}
+	}
+	snap := s.capture(description)
+	snap.coalesce = true
+	s.history.undo = push(s.history.undo, snap)
+	s.history.redo = nil
+}
+
+// capture returns a snapshot of the given bank slots, or of the active
+// pattern if none given.
+func (s *sequencer) capture(description string, patterns ...int) snapshot {
+	if len(patterns) == 0 {
+		patterns = []int{s.bank.Active}
+	}
+	snap := snapshot{
+		description: description,
+		patterns:    map[int]filesystem.Pattern{},
+	}
+	for _, p := range patterns {
+		if p != s.bank.Active {
+			snap.patterns[p] = copyPattern(s.bank.Patterns[p])
+			continue
+		}
+		snap.patterns[p] = copyPattern(s.pattern())
+		for _, t := range s.tracks {
+			snap.active = append(snap.active, t.active)
+		}
+	}
+	return snap
+}
+
+// restore applies the given snapshot and returns a snapshot of the state it
+// replaced, so that the operation can be reverted.
+//
+// If the snapshot concerns the active pattern, tracks are rebuilt but keep
+// their playhead position. All the patterns are written to the bank, as the
+// replaced state may already be saved (e.g. a pasted or cleared pattern).
+func (s *sequencer) restore(snap snapshot) snapshot {
+	var patterns []int
+	for p := range snap.patterns {
+		patterns = append(patterns, p)
+	}
+	current := s.capture(snap.description, patterns...)
+	current.coalesce = snap.coalesce
+
+	shouldSave := false
+	for p, pattern := range snap.patterns {
+		shouldSave = true
+		if p != s.bank.Active {
+			s.bank.Patterns[p] = pattern
+			continue
+		}
+
+		// Like Save, we don't keep patterns that don't play anything.
+		s.bank.Patterns[p] = filesystem.Pattern{}
+		if hasActiveSteps(pattern) {
+			s.bank.Patterns[p] = copyPattern(pattern)
+		}
+		s.reload(pattern)
+		for i, t := range s.tracks {
+			if i < len(snap.active) {
+				t.active = snap.active[i]
+			}
+		}
+	}
+
+	if shouldSave {
+		s.bank.Save()
+	}
+
+	return current
+}
@@ -0,0 +1,307 @@
+package sequencer
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+
+	"sektron/filesystem"
+	"sektron/midi"
+
+	"gitlab.com/gomidi/midi/v2/smf"
+)
+
+// Import reads the given Standard MIDI File into the given pattern slot
+// (check importPattern). Like PastePattern, it can be undone.
+func (s *sequencer) Import(filename string, pattern int) error {
+	file, err := smf.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	p, err := importPattern(file)
+	if err != nil {
+		return err
+	}
+	return s.SetPattern(pattern, p)
+}
+
+// importedTrack holds the notes and controls of a midi channel of a file
+// track, timestamped in pulses.
+type importedTrack struct {
+	channel  uint8
+	notes    []importedNote
+	controls []importedControl
+}
+
+type importedNote struct {
+	pulse    int
+	length   int
+	note     uint8
+	velocity uint8
+}
+
+type importedControl struct {
+	pulse      int
+	controller uint8
+	value      uint8
+}
+
+// importPattern converts a Standard MIDI File to a pattern. Each channel of
+// each file track with notes becomes a sektron track:
+//   - notes are quantized to steps, the remaining pulses being the step
+//     offset. The notes starting on the same pulse with the same length and
+//     velocity are played as a chord. The other notes starting on the same
+//     step go to another track on the same channel (check voices)
+//   - note lengths are converted to pulses, notes that don't end lasting a
+//     step
+//   - Control Changes become the track controls, locked on the steps where
+//     the controller value changed
+//   - notes out of the chord range are moved by octaves to fit in
+//
+// The tracks all have the same number of steps, a multiple of 16 up to
+// maxSteps. The first tempo of the file is the pattern tempo. An error is
+// returned if the file doesn't fit in a pattern.
+func importPattern(file *smf.SMF) (filesystem.Pattern, error) {
+	ticks, ok := file.TimeFormat.(smf.MetricTicks)
+	if !ok {
+		return filesystem.Pattern{}, fmt.Errorf("unsupported time format %s", file.TimeFormat)
+	}
+	ticksPerPulse := float64(ticks.Resolution()) / float64(pulsesPerQuarterNote)
+
+	var tracks []*importedTrack
+	lastPulse := 0
+	for _, events := range file.Tracks {
+		byChannel := map[uint8]*importedTrack{}
+		playing := map[[2]uint8]int{}
+		var tick int64
+		for _, event := range events {
+			tick += int64(event.Delta)
+			pulse := int(math.Round(float64(tick) / ticksPerPulse))
+
+			var channel, data1, data2 uint8
+			msg := event.Message
+			if !msg.GetChannel(&channel) {
+				continue
+			}
+			t, ok := byChannel[channel]
+			if !ok {
+				t = &importedTrack{channel: channel}
+				byChannel[channel] = t
+			}
+
+			switch {
+			case msg.GetNoteStart(&channel, &data1, &data2):
+				playing[[2]uint8{channel, data1}] = len(t.notes)
+				t.notes = append(t.notes, importedNote{
+					pulse:    pulse,
+					length:   pulsesPerStep + 1,
+					note:     data1,
+					velocity: data2,
+				})
+				lastPulse = max(lastPulse, pulse)
+			case msg.GetNoteEnd(&channel, &data1):
+				if i, ok := playing[[2]uint8{channel, data1}]; ok {
+					delete(playing, [2]uint8{channel, data1})
+					// The note stops on the last pulse of its length
+					// (check step.endingPulse).
+					t.notes[i].length = pulse - t.notes[i].pulse + 1
+				}
+			case msg.GetControlChange(&channel, &data1, &data2):
+				t.controls = append(t.controls, importedControl{
+					pulse:      pulse,
+					controller: data1,
+					value:      data2,
+				})
+			}
+		}
+
+		for channel := uint8(0); channel <= maxChannel; channel++ {
+			if t, ok := byChannel[channel]; ok && len(t.notes) > 0 {
+				tracks = append(tracks, t.voices()...)
+			}
+		}
+	}
+
+	if len(tracks) == 0 {
+		return filesystem.Pattern{}, errors.New("no notes to import")
+	}
+	if len(tracks) > maxTracks {
+		return filesystem.Pattern{}, fmt.Errorf(
+			"the file needs %d tracks (one per channel of each file track, plus one per overlapping voice), a pattern holds %d tracks at most",
+			len(tracks), maxTracks,
+		)
+	}
+	steps := (lastPulse/pulsesPerStep/defaultStepsPerTrack + 1) * defaultStepsPerTrack
+	if steps > maxSteps {
+		return filesystem.Pattern{}, fmt.Errorf(
+			"the file notes span %d steps, a track holds %d steps (%d bars) at most",
+			lastPulse/pulsesPerStep+1, maxSteps, maxSteps/defaultStepsPerTrack,
+		)
+	}
+
+	tempo := defaultTempo
+	if changes := file.TempoChanges(); len(changes) > 0 {
+		// The file tempo is stored in microseconds per quarter note, we
+		// round it to the tempo resolution of the ui.
+		tempo = math.Min(math.Max(math.Round(changes[0].BPM*10)/10, tempoMin), tempoMax)
+	}
+	pattern := filesystem.Pattern{Tempo: tempo}
+	for _, t := range tracks {
+		pattern.Tracks = append(pattern.Tracks, t.track(steps))
+	}
+	return pattern, nil
+}
+
+// track converts the imported notes and controls to a track of the given
+// number of steps. The track parameters are the ones of its first step.
+func (t importedTrack) track(steps int) filesystem.Track {
+	track := filesystem.Track{
+		Steps:       make([]filesystem.Step, steps),
+		Channel:     t.channel,
+		Controls:    map[int]int16{},
+		Probability: defaultProbability,
+	}
+	for i := range track.Steps {
+		track.Steps[i].Controls = map[int]int16{}
+	}
+
+	// pulses holds the pulse each active step starts on, to lock the
+	// controls.
+	pulses := map[int]int{}
+	for i, note := range t.notes {
+		position := note.pulse / pulsesPerStep
+		stp := &track.Steps[position]
+		length := min(max(note.length, minLength), maxLength-1)
+		if i == 0 {
+			track.Chord = []uint8{fitNote(note.note)}
+			track.Length = length
+			track.Velocity = note.velocity
+		}
+
+		if !stp.Active {
+			stp.Active = true
+			stp.Offset = note.pulse % pulsesPerStep
+			pulses[position] = note.pulse
+			if length != track.Length {
+				stp.Length = &length
+			}
+			if note.velocity != track.Velocity {
+				velocity := note.velocity
+				stp.Velocity = &velocity
+			}
+			if fitNote(note.note) != track.Chord[0] {
+				chord := []uint8{fitNote(note.note)}
+				stp.Chord = &chord
+			}
+			continue
+		}
+
+		// The step is already active with the same pulse, length and
+		// velocity (check voices): the note is added to its chord.
+		chord := slices.Clone(track.Chord)
+		if stp.Chord != nil {
+			chord = *stp.Chord
+		}
+		if !slices.Contains(chord, fitNote(note.note)) {
+			chord = append(chord, fitNote(note.note))
+			stp.Chord = &chord
+		}
+	}
+
+	for _, controller := range t.controllers() {
+		control := midi.CCControl(controller)
+		values := map[int]int16{}
+		var trackValue int16
+		isSet := false
+		for position := range track.Steps {
+			pulse, ok := pulses[position]
+			if !ok {
+				continue
+			}
+			value, ok := t.valueAt(controller, pulse)
+			if !ok {
+				continue
+			}
+			values[position] = value
+			if !isSet {
+				trackValue, isSet = value, true
+			}
+		}
+		if !isSet {
+			trackValue, _ = t.valueAt(controller, math.MaxInt)
+		}
+		track.Controls[control] = trackValue
+		for position, value := range values {
+			if value != trackValue {
+				track.Steps[position].Controls[control] = value
+			}
+		}
+	}
+	return track
+}
+
+// voices splits the notes into as many tracks as needed to keep the offset,
+// length and velocity of every note: a note goes to the first track that has
+// no note on its step, or one it can be played as a chord with. The controls
+// are kept on the first track only, so that they are sent once.
+func (t *importedTrack) voices() []*importedTrack {
+	var voices []*importedTrack
+	var firsts []map[int]importedNote
+	for _, note := range t.notes {
+		position := note.pulse / pulsesPerStep
+		i := slices.IndexFunc(firsts, func(notes map[int]importedNote) bool {
+			first, ok := notes[position]
+			return !ok || (first.pulse == note.pulse && first.length == note.length && first.velocity == note.velocity)
+		})
+		if i < 0 {
+			i = len(voices)
+			voices = append(voices, &importedTrack{channel: t.channel})
+			firsts = append(firsts, map[int]importedNote{})
+		}
+		if _, ok := firsts[i][position]; !ok {
+			firsts[i][position] = note
+		}
+		voices[i].notes = append(voices[i].notes, note)
+	}
+	voices[0].controls = t.controls
+	return voices
+}
+
+// controllers returns the controller numbers of the imported Control
+// Changes, in order of appearance.
+func (t importedTrack) controllers() []uint8 {
+	var controllers []uint8
+	for _, c := range t.controls {
+		if !slices.Contains(controllers, c.controller) {
+			controllers = append(controllers, c.controller)
+		}
+	}
+	return controllers
+}
+
+// valueAt returns the value of the given controller at the given pulse, i.e.
+// the last one received. It returns false if none was received yet.
+func (t importedTrack) valueAt(controller uint8, pulse int) (int16, bool) {
+	value, ok := int16(0), false
+	for _, c := range t.controls {
+		if c.pulse > pulse {
+			break
+		}
+		if c.controller == controller {
+			value, ok = int16(c.value), true
+		}
+	}
+	return value, ok
+}
+
+// fitNote moves the given note by octaves to fit in the chord notes range.
+func fitNote(note uint8) uint8 {
+	for note < minChordNote {
+		note += 12
+	}
+	for note > maxChordNote {
+		note -= 12
+	}
+	return note
+}
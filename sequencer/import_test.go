@@ -0,0 +1,177 @@
+package sequencer
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"sektron/filesystem"
+	"sektron/midi"
+
+	"gitlab.com/gomidi/midi/v2/smf"
+)
+
+// newTestTrack returns a track of the given number of steps, in the form
+// importPattern returns it.
+func newTestTrack(channel uint8, chord []uint8, length int, velocity uint8, steps int) filesystem.Track {
+	track := filesystem.Track{
+		Steps:       make([]filesystem.Step, steps),
+		Channel:     channel,
+		Controls:    map[int]int16{},
+		Length:      length,
+		Chord:       chord,
+		Velocity:    velocity,
+		Probability: defaultProbability,
+	}
+	for i := range track.Steps {
+		track.Steps[i].Controls = map[int]int16{}
+	}
+	return track
+}
+
+// export renders the given pattern to a Standard MIDI File and returns its
+// content.
+func export(t *testing.T, pattern filesystem.Pattern) []byte {
+	t.Helper()
+	seq := &sequencer{
+		bank: filesystem.Bank{
+			Patterns: []filesystem.Pattern{pattern, {}},
+			Active:   1,
+		},
+		output: &recorder{names: []string{"test"}},
+	}
+	file, err := seq.render([]int{0}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if _, err := file.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// roundTrip exports the given pattern and imports it back.
+func roundTrip(t *testing.T, pattern filesystem.Pattern) filesystem.Pattern {
+	t.Helper()
+	read, err := smf.ReadFrom(bytes.NewReader(export(t, pattern)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	imported, err := importPattern(read)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return imported
+}
+
+func TestExportImport(t *testing.T) {
+	cutoff := midi.CCControl(74)
+	length, velocity, chord := 18, uint8(80), []uint8{72, 76}
+
+	lead := newTestTrack(0, []uint8{60}, 12, 100, 32)
+	lead.Controls[cutoff] = 64
+	lead.Steps[0].Active = true
+	lead.Steps[4] = filesystem.Step{
+		Active:   true,
+		Controls: map[int]int16{},
+		Length:   &length,
+		Velocity: &velocity,
+		Offset:   2,
+	}
+	lead.Steps[8] = filesystem.Step{
+		Active:   true,
+		Controls: map[int]int16{cutoff: 100},
+		Chord:    &chord,
+	}
+	lead.Steps[12].Active = true
+
+	// The imported tracks all have the number of steps of the longest one.
+	drums := newTestTrack(9, []uint8{36}, 6, 127, 32)
+	for i := 0; i < len(drums.Steps); i += 4 {
+		drums.Steps[i].Active = true
+	}
+	drums.Steps[30].Active = true
+
+	single := newTestTrack(3, []uint8{48}, 6, 90, 16)
+	single.Steps[3].Active = true
+
+	// The imported track chord is the first note of the first step, the
+	// other notes are locked on the steps.
+	chords := newTestTrack(1, []uint8{60, 64, 67}, 12, 100, 16)
+	chords.Steps[0].Active = true
+	chords.Steps[8].Active = true
+
+	tests := []struct {
+		name    string
+		pattern filesystem.Pattern
+		// same is true if the imported pattern is the exported one, false
+		// if only the notes it plays are the same.
+		same bool
+	}{
+		{
+			name:    "single track",
+			pattern: filesystem.Pattern{Tempo: 98.5, Tracks: []filesystem.Track{single}},
+			same:    true,
+		},
+		{
+			name:    "offsets, lengths and controls",
+			pattern: filesystem.Pattern{Tempo: 140, Tracks: []filesystem.Track{lead, drums}},
+			same:    true,
+		},
+		{
+			name:    "track chord",
+			pattern: filesystem.Pattern{Tempo: 120, Tracks: []filesystem.Track{chords}},
+		},
+	}
+	for _, test := range tests {
+		got := roundTrip(t, test.pattern)
+		if test.same && !reflect.DeepEqual(got, test.pattern) {
+			t.Errorf("%s: got %+v, want %+v", test.name, got, test.pattern)
+		}
+		if !bytes.Equal(export(t, got), export(t, test.pattern)) {
+			t.Errorf("%s: the imported pattern doesn't render the same file", test.name)
+		}
+	}
+}
+
+func TestImportVoices(t *testing.T) {
+	// Two notes on the same step with different lengths can't be played as
+	// a chord: the second one goes to another track on the same channel.
+	file := smf.NewSMF1()
+	file.TimeFormat = smf.MetricTicks(pulsesPerQuarterNote)
+	var track smf.Track
+	track.Add(0, smf.Message{0x90, 60, 100})
+	track.Add(0, smf.Message{0x90, 64, 100})
+	track.Add(0, smf.Message{0x90, 67, 100})
+	track.Add(5, smf.Message{0x80, 60, 0})
+	track.Add(0, smf.Message{0x80, 64, 0})
+	track.Add(6, smf.Message{0x80, 67, 0})
+	track.Close(0)
+	file.Add(track)
+
+	pattern, err := importPattern(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		chord  []uint8
+		length int
+	}{
+		{[]uint8{60, 64}, 6},
+		{[]uint8{67}, 12},
+	}
+	if len(pattern.Tracks) != len(want) {
+		t.Fatalf("got %d tracks, want %d", len(pattern.Tracks), len(want))
+	}
+	for i, w := range want {
+		track := pattern.Tracks[i]
+		chord := track.Chord
+		if track.Steps[0].Chord != nil {
+			chord = *track.Steps[0].Chord
+		}
+		if !track.Steps[0].Active || !reflect.DeepEqual(chord, w.chord) || track.Length != w.length {
+			t.Errorf("track %d: got chord %v and length %d, want %v and %d", i, chord, track.Length, w.chord, w.length)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package sequencer
+
+import (
+	"math"
+	"time"
+
+	"sektron/link"
+)
+
+// When linked, playing starts on the next bar of the session (4 beats).
+const linkQuantum int = 4
+
+// EnableLink makes the sequencer join an Ableton Link session (check the
+// link package): the clock follows the session tempo and beat grid, and the
+// tempo changes are shared with the other peers. Tempo ramps and nudges are
+// disabled while linked.
+func (s *sequencer) EnableLink() error {
+	if s.link != nil {
+		return nil
+	}
+	l, err := link.New(s.Tempo(), func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.alignToLink()
+	})
+	if err != nil {
+		return err
+	}
+	s.link = l
+	s.alignToLink()
+	return nil
+}
+
+// DisableLink leaves the Ableton Link session.
+func (s *sequencer) DisableLink() {
+	if s.link == nil {
+		return
+	}
+	s.link.Close()
+	s.link = nil
+}
+
+// LinkPeers returns the number of other peers in the Ableton Link session,
+// if linked.
+func (s *sequencer) LinkPeers() (int, bool) {
+	l := s.link
+	if l == nil {
+		return 0, false
+	}
+	return l.Peers(), true
+}
+
+// alignToLink sets the clock to the session tempo and schedules the next
+// pulse on the session beat grid.
+func (s *sequencer) alignToLink() {
+	l := s.link
+	if l == nil {
+		return
+	}
+	tempo := l.Tempo()
+	pulses := float64(pulsesPerStep * stepsPerQuarterNote)
+	next := (math.Floor(l.BeatAt(time.Now())*pulses) + 1) / pulses
+	s.clock.alignTo(tempo, l.TimeAt(next))
+	s.rampStart = tempo
+}
+
+// isOnLinkBar returns true if the current pulse is the first one of a
+// session bar.
+func (s *sequencer) isOnLinkBar() bool {
+	pulses := pulsesPerStep * stepsPerQuarterNote
+	pulse := int(math.Round(s.link.BeatAt(time.Now()) * float64(pulses)))
+	return mod(pulse, linkQuantum*pulses) == 0
+}
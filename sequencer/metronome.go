@@ -0,0 +1,107 @@
+package sequencer
+
+import (
+	"sektron/filesystem"
+)
+
+const (
+	pulsesPerQuarterNote int = pulsesPerStep * stepsPerQuarterNote
+
+	// The click lasts one step.
+	clickLength int = pulsesPerStep
+)
+
+// metronome holds the metronome state (check filesystem.Metronome).
+// The pulse counts the pulses since playing started, count-in included, so
+// that we know when to click and accent.
+type metronome struct {
+	enabled    bool
+	device     int
+	channel    uint8
+	note       uint8
+	accentNote uint8
+	velocity   uint8
+	countIn    int
+	pulse      int
+	playing    *uint8
+}
+
+// SetMetronome sets the metronome settings. If the device isn't connected,
+// the first one is used.
+func (s *sequencer) SetMetronome(settings filesystem.Metronome) {
+	s.stopClick()
+	device := defaultDevice
+	for i, name := range s.OutputDevices() {
+		if name == settings.Device {
+			device = i
+			break
+		}
+	}
+	s.metronome = metronome{
+		enabled:    settings.Enabled,
+		device:     device,
+		channel:    settings.Channel,
+		note:       settings.Note,
+		accentNote: settings.AccentNote,
+		velocity:   settings.Velocity,
+		countIn:    settings.CountIn,
+	}
+}
+
+// CountIn returns the number of beats left before playing starts, while
+// counting in.
+func (s *sequencer) CountIn() (int, bool) {
+	if !s.isPlaying || s.countIn <= 0 {
+		return 0, false
+	}
+	return (s.countIn + pulsesPerQuarterNote - 1) / pulsesPerQuarterNote, true
+}
+
+// startCountIn starts counting in before playing from the beginning. It
+// returns false if there's no count-in. When synced to an external clock,
+// the master decides when to start, so there's no count-in.
+func (s *sequencer) startCountIn() bool {
+	s.metronome.pulse = 0
+	s.countIn = 0
+	if s.external == nil {
+		s.countIn = s.metronome.countIn * pulsesPerBar
+	}
+	return s.countIn > 0
+}
+
+// tickMetronome clicks on every quarter note while playing, or while
+// counting in even if the metronome is disabled.
+func (s *sequencer) tickMetronome() {
+	m := &s.metronome
+	defer func() {
+		m.pulse++
+	}()
+
+	if m.pulse%pulsesPerQuarterNote == clickLength {
+		s.stopClick()
+	}
+	if !m.enabled && s.countIn <= 0 {
+		return
+	}
+	if m.pulse%pulsesPerQuarterNote != 0 {
+		return
+	}
+
+	s.stopClick()
+	note := m.note
+	if m.pulse%pulsesPerBar == 0 {
+		note = m.accentNote
+	}
+	s.midi.NoteOn(m.device, m.channel, note, m.velocity)
+	m.playing = &note
+}
+
+// stopClick stops the playing click, if any.
+func (s *sequencer) stopClick() {
+	m := &s.metronome
+	if m.playing == nil {
+		return
+	}
+	s.midi.NoteOff(m.device, m.channel, *m.playing)
+	m.playing = nil
+}
@@ -1,72 +1,216 @@
 package sequencer
 
 import (
+	"errors"
+	"fmt"
+
 	"sektron/filesystem"
 	"sektron/midi"
 )
 
 // Patterns returns all patterns from the bank.
-func (s sequencer) Patterns() []filesystem.Pattern {
+func (s *sequencer) Patterns() []filesystem.Pattern {
 	return s.bank.Patterns
 }
 
 // ActivePattern returns the active pattern.
-func (s sequencer) ActivePattern() int {
+func (s *sequencer) ActivePattern() int {
 	return s.bank.Active
 }
 
-// Save saves the current sequencer state to the active pattern.
-func (s *sequencer) Save() {
-	var tracks []filesystem.Track
-	shouldSave := false
-	for _, t := range s.Tracks() {
-		var steps []filesystem.Step
-		controls := map[int]int16{}
-		for k := range t.activeControls {
-			controls[k] = t.controls[k].Value()
-		}
+// Pattern returns a copy of the given pattern. The active pattern is taken
+// from the running tracks, so it includes the unsaved edits.
+func (s *sequencer) Pattern(pattern int) filesystem.Pattern {
+	if pattern == s.bank.Active {
+		return copyPattern(s.pattern())
+	}
+	return copyPattern(s.bank.Patterns[pattern])
+}
+
+// SetPattern replaces the given bank slot with the given pattern, like
+// PastePattern. It returns an error if the pattern is not valid.
+func (s *sequencer) SetPattern(pattern int, p filesystem.Pattern) error {
+	if pattern < 0 || pattern >= len(s.bank.Patterns) {
+		return fmt.Errorf("invalid pattern %d", pattern)
+	}
+	if err := validatePattern(p); err != nil {
+		return err
+	}
+	s.record(fmt.Sprintf("replace pattern %d", pattern+1), pattern)
+	s.replacePattern(pattern, copyPattern(p))
+	return nil
+}
 
-		for _, s := range t.Steps() {
-			if !shouldSave && s.active {
-				shouldSave = true
+// validatePattern checks that a pattern coming from outside of the
+// sequencer can be loaded. Out of range devices are replaced on load (check
+// track.load), like with the bank file.
+func validatePattern(p filesystem.Pattern) error {
+	if p.IsFree() {
+		return nil
+	}
+	if len(p.Tracks) < minTracks || len(p.Tracks) > maxTracks {
+		return fmt.Errorf("a pattern must have %d to %d tracks", minTracks, maxTracks)
+	}
+	for i, t := range p.Tracks {
+		if err := validateParameters(t.Chord, t.Length, t.Velocity, t.Probability); err != nil {
+			return fmt.Errorf("track %d: %w", i, err)
+		}
+		if t.Channel > maxChannel {
+			return fmt.Errorf("track %d: invalid channel %d", i, t.Channel)
+		}
+		if len(t.Steps) < minSteps || len(t.Steps) > maxSteps {
+			return fmt.Errorf("track %d: a track must have %d to %d steps", i, minSteps, maxSteps)
+		}
+		if err := validateControls(t.Controls); err != nil {
+			return fmt.Errorf("track %d: %w", i, err)
+		}
+		for j, stp := range t.Steps {
+			if stp.Chord != nil || stp.Length != nil || stp.Velocity != nil || stp.Probability != nil {
+				chord, length, velocity, probability := t.Chord, t.Length, t.Velocity, t.Probability
+				if stp.Chord != nil {
+					chord = *stp.Chord
+				}
+				if stp.Length != nil {
+					length = *stp.Length
+				}
+				if stp.Velocity != nil {
+					velocity = *stp.Velocity
+				}
+				if stp.Probability != nil {
+					probability = *stp.Probability
+				}
+				if err := validateParameters(chord, length, velocity, probability); err != nil {
+					return fmt.Errorf("track %d step %d: %w", i, j, err)
+				}
+			}
+			if stp.Offset < minOffset || stp.Offset > maxOffset {
+				return fmt.Errorf("track %d step %d: invalid offset %d", i, j, stp.Offset)
 			}
-			stepControls := map[int]int16{}
-			for k, c := range s.controls {
-				stepControls[k] = c.Value()
+			if err := validateControls(stp.Controls); err != nil {
+				return fmt.Errorf("track %d step %d: %w", i, j, err)
 			}
-			steps = append(steps, filesystem.Step{
-				Active:      s.active,
-				Controls:    stepControls,
-				Length:      s.length,
-				Chord:       s.chord,
-				Velocity:    s.velocity,
-				Probability: s.probability,
-				Offset:      s.offset,
-			})
 		}
+	}
+	return nil
+}
+
+func validateParameters(chord []uint8, length int, velocity uint8, probability int) error {
+	if len(chord) == 0 {
+		return errors.New("empty chord")
+	}
+	for _, note := range chord {
+		if note < minChordNote || note > maxChordNote {
+			return fmt.Errorf("invalid note %d", note)
+		}
+	}
+	if length < minLength || length > maxLength {
+		return fmt.Errorf("invalid length %d", length)
+	}
+	if velocity > maxVelocity {
+		return fmt.Errorf("invalid velocity %d", velocity)
+	}
+	if probability < minProbability || probability > maxProbability {
+		return fmt.Errorf("invalid probability %d", probability)
+	}
+	return nil
+}
 
-		tracks = append(tracks, filesystem.Track{
-			Steps:       steps,
-			Device:      t.device, // TODO: should we store the name instead?
-			Channel:     t.channel,
-			Controls:    controls,
-			Length:      t.length,
-			Chord:       t.chord,
-			Velocity:    t.velocity,
-			Probability: t.probability,
-		})
+func validateControls(controls map[int]int16) error {
+	for control := range controls {
+		if control < 0 || control >= midi.ControlsCount {
+			return fmt.Errorf("invalid control %d", control)
+		}
 	}
+	return nil
+}
 
-	if !shouldSave {
+// Save saves the current sequencer state to the active pattern.
+func (s *sequencer) Save() {
+	pattern := s.pattern()
+	if !hasActiveSteps(pattern) {
 		return
 	}
 
-	s.bank.Patterns[s.bank.Active] = filesystem.Pattern{
-		Tempo:  s.Tempo(),
+	s.bank.Patterns[s.bank.Active] = pattern
+	s.bank.Save()
+}
+
+// pattern returns the current sequencer state as a serializable Pattern.
+func (s *sequencer) pattern() filesystem.Pattern {
+	var tracks []filesystem.Track
+	for _, t := range s.Tracks() {
+		tracks = append(tracks, t.serialize())
+	}
+
+	// While ramping, the tempo changes all the time. We save the tempo the
+	// ramp starts from instead.
+	tempo := s.Tempo()
+	var ramp *filesystem.TempoRamp
+	if s.ramp != nil {
+		tempo = s.rampStart
+		r := *s.ramp
+		ramp = &r
+	}
+
+	return filesystem.Pattern{
+		Tempo:  tempo,
 		Tracks: tracks,
+		Ramp:   ramp,
 	}
+}
 
-	s.bank.Save()
+// serialize returns the track state as a serializable Track.
+func (t track) serialize() filesystem.Track {
+	var steps []filesystem.Step
+	controls := map[int]int16{}
+	for k := range t.activeControls {
+		controls[k] = t.controls[k].Value()
+	}
+
+	for _, s := range t.Steps() {
+		steps = append(steps, s.serialize())
+	}
+
+	return filesystem.Track{
+		Steps:       steps,
+		Device:      t.device, // TODO: should we store the name instead?
+		Channel:     t.channel,
+		Controls:    controls,
+		Length:      t.length,
+		Chord:       t.chord,
+		Velocity:    t.velocity,
+		Probability: t.probability,
+	}
+}
+
+// serialize returns the step state as a serializable Step.
+func (s step) serialize() filesystem.Step {
+	controls := map[int]int16{}
+	for k, c := range s.controls {
+		controls[k] = c.Value()
+	}
+	return filesystem.Step{
+		Active:      s.active,
+		Controls:    controls,
+		Length:      s.length,
+		Chord:       s.chord,
+		Velocity:    s.velocity,
+		Probability: s.probability,
+		Offset:      s.offset,
+	}
+}
+
+// hasActiveSteps returns true if at least one step of the pattern is active.
+// We don't want to save patterns that don't play anything.
+func hasActiveSteps(pattern filesystem.Pattern) bool {
+	for _, t := range pattern.Tracks {
+		for _, s := range t.Steps {
+			if s.Active {
+				return true
+			}
+		}
+	}
+	return false
 }
 
 // FullChain returns the full pattern chain.
@@ -98,71 +242,131 @@ func (s *sequencer) LoadNextInChain() {
 
 // Load loads a new sequencer state from Pattern object.
 func (s *sequencer) Load(pattern int) {
+	s.bank.Active = pattern
+	s.stopRamp()
+	s.ramp = nil
+	// When linked, the session tempo wins over the pattern one.
+	if !s.bank.Patterns[pattern].IsFree() && s.link == nil {
+		s.SetTempo(s.bank.Patterns[pattern].Tempo)
+		if ramp := s.bank.Patterns[pattern].Ramp; ramp != nil {
+			s.SetTempoRamp(*ramp)
+		}
+	}
+	s.load(s.bank.Patterns[pattern])
+}
+
+// load replaces the sequencer tracks with the ones described in the given
+// pattern. If the pattern is free, we instanciate the default number of tracks.
+func (s *sequencer) load(pattern filesystem.Pattern) {
 	// close existing tracks first
 	for _, t := range s.tracks {
 		t.reset()
 		t.close()
 	}
 	s.tracks = []*track{}
-	s.bank.Active = pattern
 
-	if s.bank.Patterns[pattern].Tracks == nil {
+	if pattern.IsFree() {
 		for i := 0; i < defaultTracks; i++ {
-			s.AddTrack()
+			s.addTrack()
 		}
 		return
 	}
 
-	s.SetTempo(s.bank.Patterns[pattern].Tempo)
-
-	for i, t := range s.bank.Patterns[pattern].Tracks {
-		// Check if midi device exists or set the first one found.
-		if len(s.midi.Devices()) < t.Device+1 {
-			t.Device = 0
-		}
-
-		s.tracks = append(s.tracks, &track{
-			midi:                  s.midi,
+	for _, t := range pattern.Tracks {
+		track := &track{
+			output:                s.output,
 			seq:                   s,
-			steps:                 []*step{},
-			chord:                 t.Chord,
-			length:                t.Length,
-			velocity:              t.Velocity,
-			probability:           t.Probability,
-			device:                t.Device,
-			channel:               t.Channel,
 			activeControls:        map[int]struct{}{},
 			lastSentControlValues: map[int]int16{},
 			active:                true,
-		})
+		}
+		track.load(t)
+		track.start()
+		s.tracks = append(s.tracks, track)
+	}
+}
 
-		s.tracks[i].controls = midi.NewControls(s.midi, s.tracks[i])
+// reload works like load but keeps the playhead position and the activation
+// state of the existing tracks. Useful when the active pattern is modified
+// while playing.
+func (s *sequencer) reload(pattern filesystem.Pattern) {
+	pulses := make([]int, len(s.tracks))
+	active := make([]bool, len(s.tracks))
+	for i, t := range s.tracks {
+		pulses[i] = t.pulse
+		active[i] = t.active
+	}
 
-		for k, v := range t.Controls {
-			s.tracks[i].controls[k].Set(v)
-			s.tracks[i].activeControls[k] = struct{}{}
-		}
+	s.load(pattern)
 
-		s.tracks[i].steps = []*step{}
-		for j, stp := range t.Steps {
-			s.tracks[i].steps = append(s.tracks[i].steps, &step{
-				position:    j,
-				midi:        s.midi,
-				track:       s.tracks[i],
-				active:      stp.Active,
-				length:      stp.Length,
-				chord:       stp.Chord,
-				velocity:    stp.Velocity,
-				probability: stp.Probability,
-				offset:      stp.Offset,
-				controls:    map[int]*midi.Control{},
-			})
-
-			for k, v := range stp.Controls {
-				s.tracks[i].steps[j].SetControl(k, v)
-			}
+	for i, t := range s.tracks {
+		if i >= len(pulses) {
+			break
 		}
+		t.active = active[i]
+		if pulses[i] < len(t.steps)*pulsesPerStep {
+			t.pulse = pulses[i]
+		}
+	}
+}
+
+// load replaces the track parameters, controls and steps with the ones
+// described in the given track. The playhead position is kept if possible.
+// Playing notes should be stopped beforehand (check track.clear()).
+func (t *track) load(track filesystem.Track) {
+	// Check if the device exists or set the first one found.
+	if len(t.output.Names()) < track.Device+1 {
+		track.Device = 0
+	}
 
-		s.tracks[i].start()
+	t.device = track.Device
+	t.channel = track.Channel
+	t.chord = track.Chord
+	t.length = track.Length
+	t.velocity = track.Velocity
+	t.probability = track.Probability
+
+	t.controls = midi.NewControls(t.output, t)
+	t.activeControls = map[int]struct{}{}
+	t.lastSentControlValues = map[int]int16{}
+	for k, v := range track.Controls {
+		t.controls[k].Set(v)
+		t.activeControls[k] = struct{}{}
+	}
+
+	t.steps = []*step{}
+	for j, stp := range track.Steps {
+		t.steps = append(t.steps, newStep(t, j, stp))
 	}
+
+	t.lastTriggeredStep = 0
+	if t.pulse >= len(t.steps)*pulsesPerStep {
+		t.pulse = 0
+	}
+}
+
+// newStep creates a new step for the given track from a serialized Step.
+func newStep(t *track, position int, stp filesystem.Step) *step {
+	step := &step{
+		position:    position,
+		output:      t.output,
+		track:       t,
+		active:      stp.Active,
+		length:      stp.Length,
+		chord:       stp.Chord,
+		velocity:    stp.Velocity,
+		probability: stp.Probability,
+		offset:      stp.Offset,
+		controls:    map[int]*midi.Control{},
+	}
+
+	// We don't use SetControl here as it would be recorded in the undo
+	// history.
+	for k, v := range stp.Controls {
+		control := t.controls[k]
+		control.Set(v)
+		step.controls[k] = &control
+	}
+
+	return step
 }
@@ -0,0 +1,57 @@
+package sequencer
+
+import "sektron/filesystem"
+
+const (
+	minRampBars int = 1
+	maxRampBars int = 64
+)
+
+// TempoRamp returns the active pattern tempo ramp, if any.
+func (s *sequencer) TempoRamp() (filesystem.TempoRamp, bool) {
+	if s.ramp == nil {
+		return filesystem.TempoRamp{}, false
+	}
+	return *s.ramp, true
+}
+
+// SetTempoRamp sets the active pattern tempo ramp. When the pattern starts
+// playing, the tempo goes from the pattern tempo to the ramp target over the
+// ramp number of bars. A ramp with no bars removes the tempo ramp.
+func (s *sequencer) SetTempoRamp(ramp filesystem.TempoRamp) {
+	if ramp.Bars < minRampBars {
+		s.stopRamp()
+		s.ramp = nil
+		return
+	}
+	if ramp.Bars > maxRampBars || ramp.Target > tempoMax || ramp.Target < tempoMin {
+		return
+	}
+	s.ramp = &ramp
+	if s.isPlaying {
+		s.startRamp()
+	}
+}
+
+// IsRamping returns true if the tempo is currently ramping, and the tempo it
+// is ramping to.
+func (s *sequencer) IsRamping() (float64, bool) {
+	return s.clock.rampTarget()
+}
+
+// startRamp makes the clock go from the pattern tempo to the ramp target.
+func (s *sequencer) startRamp() {
+	// When linked, the session tempo wins.
+	if s.ramp == nil || s.link != nil {
+		return
+	}
+	s.clock.setRamp(s.rampStart, s.ramp.Target, s.ramp.Bars*pulsesPerBar)
+}
+
+// stopRamp stops the running ramp and goes back to the pattern tempo.
+func (s *sequencer) stopRamp() {
+	if s.ramp == nil || s.link != nil {
+		return
+	}
+	s.clock.setTempo(s.rampStart)
+}
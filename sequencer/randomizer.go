@@ -0,0 +1,66 @@
+package sequencer
+
+import (
+	"fmt"
+	"math/rand"
+
+	"sektron/filesystem"
+)
+
+// Randomize generates new steps for the given track, following the
+// randomizer constraints. Parameters are set on each generated step
+// (parameter locks), the track parameters are left untouched.
+//
+// Using the same seed and constraints always generates the same steps.
+func (s *sequencer) Randomize(track int, settings filesystem.Randomizer) {
+	if track < 0 || track >= len(s.tracks) {
+		return
+	}
+	s.record(fmt.Sprintf("randomize track %d (seed %d)", track+1, settings.Seed))
+
+	noteFrom, noteTo := clampedRange(int(settings.MinNote), int(settings.MaxNote), minChordNote, maxChordNote)
+	velocityFrom, velocityTo := clampedRange(int(settings.MinVelocity), int(settings.MaxVelocity), minVelocity, maxVelocity)
+	lengthFrom, lengthTo := clampedRange(settings.MinLength, settings.MaxLength, minLength, pulsesPerStep*maxSteps)
+	probabilityFrom, probabilityTo := clampedRange(settings.MinProbability, settings.MaxProbability, minProbability, maxProbability)
+
+	notes := scaleNotes(settings.Scale, settings.Root%12, uint8(noteFrom), uint8(noteTo))
+	if len(notes) == 0 {
+		notes = []uint8{uint8(noteFrom)}
+	}
+
+	r := rand.New(rand.NewSource(settings.Seed))
+	t := s.tracks[track]
+	for i, stp := range t.steps {
+		// We always draw every value, even for inactive steps, so that the
+		// same seed gives the same rhythm whatever the other constraints.
+		active := r.Intn(100) < settings.Density
+		chord := []uint8{notes[r.Intn(len(notes))]}
+		velocity := uint8(randomInRange(r, velocityFrom, velocityTo))
+		length := randomInRange(r, lengthFrom, lengthTo)
+		probability := randomInRange(r, probabilityFrom, probabilityTo)
+
+		generated := filesystem.Step{Active: active}
+		if active {
+			generated.Chord = &chord
+			generated.Velocity = &velocity
+			generated.Length = &length
+			generated.Probability = &probability
+		}
+
+		stp.reset()
+		t.steps[i] = newStep(t, i, generated)
+	}
+}
+
+// clampedRange returns the min and max values, in the right order and
+// restricted to the given limits.
+func clampedRange(minValue, maxValue, lower, upper int) (int, int) {
+	if minValue > maxValue {
+		minValue, maxValue = maxValue, minValue
+	}
+	return max(lower, min(minValue, upper)), max(lower, min(maxValue, upper))
+}
+
+func randomInRange(r *rand.Rand, minValue, maxValue int) int {
+	return minValue + r.Intn(maxValue-minValue+1)
+}
@@ -0,0 +1,88 @@
+package sequencer
+
+import (
+	"fmt"
+	"math"
+
+	"sektron/filesystem"
+	"sektron/midi"
+)
+
+// EnableRemote makes the sequencer respond to the MIDI Machine Control
+// commands and the Program Changes received on the input of the given
+// settings:
+//   - Play and Deferred Play start playing
+//   - Stop and Pause stop playing
+//   - Locate moves the playhead to the given time, at the current tempo
+//   - Program Change switches to the pattern of the same number, at the end
+//     of the current one when playing
+func (s *sequencer) EnableRemote(settings filesystem.Remote) error {
+	s.DisableRemote()
+	input := s.inputIndex(settings.Input)
+	if input < 0 {
+		return fmt.Errorf("remote midi input %q not found", settings.Input)
+	}
+	stop, err := s.midi.ListenRemote(input, func(msg midi.RemoteMessage) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.receiveRemote(settings, msg)
+	})
+	if err != nil {
+		return err
+	}
+	s.stopRemote = stop
+	return nil
+}
+
+// DisableRemote stops responding to the remote control messages.
+func (s *sequencer) DisableRemote() {
+	stop := s.stopRemote
+	if stop == nil {
+		return
+	}
+	s.stopRemote = nil
+	s.unlocked(stop)
+}
+
+func (s *sequencer) receiveRemote(settings filesystem.Remote, msg midi.RemoteMessage) {
+	if msg.Type == midi.RemoteProgram {
+		if int(msg.Channel) != settings.Channel || int(msg.Program) >= len(s.bank.Patterns) {
+			return
+		}
+		if s.isPlaying {
+			s.ChainNow(int(msg.Program))
+		} else {
+			s.Save()
+			s.Load(int(msg.Program))
+		}
+		return
+	}
+
+	if !msg.IsFor(settings.DeviceID) {
+		return
+	}
+	switch msg.Type {
+	case midi.RemotePlay:
+		if !s.isPlaying {
+			s.TogglePlay()
+		}
+	case midi.RemoteStop:
+		if s.isPlaying {
+			s.TogglePlay()
+		}
+	case midi.RemoteLocate:
+		steps := msg.Position.Minutes() * s.Tempo() * float64(stepsPerQuarterNote)
+		s.setPosition(int(math.Round(steps)))
+	}
+}
+
+// inputIndex returns the index of the midi input port with the given name,
+// -1 if not found.
+func (s *sequencer) inputIndex(name string) int {
+	for i, in := range s.midi.Inputs() {
+		if in.String() == name {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,226 @@
+package sequencer
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"slices"
+
+	"sektron/filesystem"
+
+	gomidi "gitlab.com/gomidi/midi/v2"
+	"gitlab.com/gomidi/midi/v2/smf"
+)
+
+// Export renders the given patterns, played one after the other like a
+// chain, to a Standard MIDI File (check render).
+func (s *sequencer) Export(filename string, patterns []int, seed int64) error {
+	file, err := s.render(patterns, seed)
+	if err != nil {
+		return err
+	}
+	return file.WriteFile(filename)
+}
+
+// render plays the given patterns one after the other, like a chain, against
+// a virtual clock and returns the result as a Standard MIDI File. Each
+// pattern lasts as long as its first track, as when chained while playing.
+//
+// The file holds a tempo track, then one track per sektron track with the
+// notes and controls it sends. One pulse is one tick. The step probabilities
+// are drawn from the given seed, so that the same seed always renders the
+// same file.
+func (s *sequencer) render(patterns []int, seed int64) (*smf.SMF, error) {
+	var toRender []int
+	for _, p := range patterns {
+		if p < 0 || p >= len(s.bank.Patterns) {
+			return nil, fmt.Errorf("invalid pattern %d", p)
+		}
+		if !s.bank.Patterns[p].IsFree() || p == s.bank.Active {
+			toRender = append(toRender, p)
+		}
+	}
+	if len(toRender) == 0 {
+		return nil, errors.New("nothing to render")
+	}
+
+	r := &renderer{
+		seq:   &sequencer{randomizer: rand.New(rand.NewSource(seed))},
+		names: s.output.Names(),
+	}
+	r.tempoTrack.add(0, smf.MetaTrackSequenceName("tempo"))
+	r.tempoTrack.add(0, smf.MetaMeter(4, 4))
+	for i, p := range toRender {
+		r.render(p, s.Pattern(p), i == 0)
+	}
+	r.clear()
+
+	file := smf.NewSMF1()
+	file.TimeFormat = smf.MetricTicks(pulsesPerQuarterNote)
+	r.tempoTrack.close(r.pulse)
+	if err := file.Add(r.tempoTrack.track); err != nil {
+		return nil, err
+	}
+	for _, output := range r.outputs {
+		output.close(r.pulse)
+		if err := file.Add(output.track); err != nil {
+			return nil, err
+		}
+	}
+	return file, nil
+}
+
+// renderer holds the offline rendering state: the tracks of the pattern
+// being rendered, triggered by the virtual clock pulse, and the outputs that
+// record their messages.
+type renderer struct {
+	seq   *sequencer
+	names []string
+	pulse int
+
+	tempo      float64
+	tempoTrack smfTrack
+	outputs    []*recorder
+}
+
+// render plays the given pattern for the length of its first track. The
+// track controls are sent first when starting, like when playing.
+func (r *renderer) render(index int, pattern filesystem.Pattern, start bool) {
+	r.clear()
+	r.load(pattern)
+	r.tempoTrack.add(r.pulse, smf.MetaMarker(fmt.Sprintf("pattern %d", index+1)))
+
+	ramp := tempoRamp{from: pattern.Tempo, to: pattern.Tempo}
+	if pattern.Ramp != nil {
+		ramp = tempoRamp{from: pattern.Tempo, to: pattern.Ramp.Target, pulses: pattern.Ramp.Bars * pulsesPerBar}
+	}
+
+	if start {
+		for _, t := range r.seq.tracks {
+			t.sendControls()
+		}
+	}
+
+	pulses := len(r.seq.tracks[0].steps) * pulsesPerStep
+	for i := 0; i < pulses; i++ {
+		r.setTempo(ramp.tempoAt())
+		ramp.pulse++
+		for _, t := range r.seq.tracks {
+			t.trigger()
+		}
+		r.pulse++
+	}
+}
+
+// load replaces the tracks with the ones of the given pattern. Unlike
+// sequencer.load, the tracks don't start a goroutine: the renderer triggers
+// them directly. Each of them sends its messages to its own recorder.
+func (r *renderer) load(pattern filesystem.Pattern) {
+	r.seq.tracks = []*track{}
+	for i, t := range pattern.Tracks {
+		if i == len(r.outputs) {
+			r.outputs = append(r.outputs, &recorder{names: r.names, pulse: &r.pulse})
+			r.outputs[i].add(0, smf.MetaTrackSequenceName(fmt.Sprintf("track %d", i+1)))
+		}
+		track := &track{
+			output:                r.outputs[i],
+			seq:                   r.seq,
+			activeControls:        map[int]struct{}{},
+			lastSentControlValues: map[int]int16{},
+			active:                true,
+		}
+		track.load(t)
+		r.seq.tracks = append(r.seq.tracks, track)
+	}
+}
+
+// clear stops the playing notes of the current tracks.
+func (r *renderer) clear() {
+	for _, t := range r.seq.tracks {
+		t.reset()
+	}
+}
+
+// setTempo adds a tempo change to the tempo track if the tempo changed.
+func (r *renderer) setTempo(tempo float64) {
+	if tempo == r.tempo {
+		return
+	}
+	r.tempo = tempo
+	r.tempoTrack.add(r.pulse, smf.MetaTempo(tempo))
+}
+
+// smfTrack builds a Standard MIDI File track from messages timestamped in
+// pulses.
+type smfTrack struct {
+	track smf.Track
+	last  int
+}
+
+func (t *smfTrack) add(pulse int, msg []byte) {
+	t.track.Add(uint32(pulse-t.last), msg)
+	t.last = pulse
+}
+
+func (t *smfTrack) close(pulse int) {
+	t.track.Close(uint32(pulse - t.last))
+}
+
+// recorder is a midi.Output that records the messages of a track at the
+// current renderer pulse. The device names are the ones of the sequencer
+// output, so that the track devices are kept on load.
+type recorder struct {
+	smfTrack
+	names   []string
+	pulse   *int
+	devices []int
+}
+
+// Names returns the sequencer output device names.
+func (r *recorder) Names() []string {
+	return r.names
+}
+
+// record adds the given message to the track, and the name of its device the
+// first time it's used.
+func (r *recorder) record(device int, msg gomidi.Message) {
+	if !slices.Contains(r.devices, device) {
+		r.devices = append(r.devices, device)
+		r.add(*r.pulse, smf.MetaDevice(r.names[device]))
+	}
+	r.add(*r.pulse, msg)
+}
+
+// NoteOn records a Note On message.
+func (r *recorder) NoteOn(device int, channel, note, velocity uint8) {
+	r.record(device, gomidi.NoteOn(channel, note, velocity))
+}
+
+// NoteOff records a Note Off message.
+func (r *recorder) NoteOff(device int, channel, note uint8) {
+	r.record(device, gomidi.NoteOff(channel, note))
+}
+
+// Silence does nothing: the notes are recorded with their Note Off message
+// (check track.clear).
+func (r *recorder) Silence(device int, channel uint8) {}
+
+// ControlChange records a Control Change message.
+func (r *recorder) ControlChange(device int, channel, controller, value uint8) {
+	r.record(device, gomidi.ControlChange(channel, controller, value))
+}
+
+// ProgramChange records a Program Change message.
+func (r *recorder) ProgramChange(device int, channel, value uint8) {
+	r.record(device, gomidi.ProgramChange(channel, value))
+}
+
+// Pitchbend records a Pitch Bend message.
+func (r *recorder) Pitchbend(device int, channel uint8, value int16) {
+	r.record(device, gomidi.Pitchbend(channel, value))
+}
+
+// AfterTouch records an After Touch message.
+func (r *recorder) AfterTouch(device int, channel, value uint8) {
+	r.record(device, gomidi.AfterTouch(channel, value))
+}
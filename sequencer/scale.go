@@ -0,0 +1,54 @@
+package sequencer
+
+// scale holds the intervals (in semitones from the root note) of a musical
+// scale.
+type scale struct {
+	name      string
+	intervals []uint8
+}
+
+var scales = []scale{
+	{name: "chromatic", intervals: []uint8{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11}},
+	{name: "major", intervals: []uint8{0, 2, 4, 5, 7, 9, 11}},
+	{name: "minor", intervals: []uint8{0, 2, 3, 5, 7, 8, 10}},
+	{name: "harmonic minor", intervals: []uint8{0, 2, 3, 5, 7, 8, 11}},
+	{name: "dorian", intervals: []uint8{0, 2, 3, 5, 7, 9, 10}},
+	{name: "phrygian", intervals: []uint8{0, 1, 3, 5, 7, 8, 10}},
+	{name: "lydian", intervals: []uint8{0, 2, 4, 6, 7, 9, 11}},
+	{name: "mixolydian", intervals: []uint8{0, 2, 4, 5, 7, 9, 10}},
+	{name: "locrian", intervals: []uint8{0, 1, 3, 5, 6, 8, 10}},
+	{name: "major pentatonic", intervals: []uint8{0, 2, 4, 7, 9}},
+	{name: "minor pentatonic", intervals: []uint8{0, 3, 5, 7, 10}},
+	{name: "blues", intervals: []uint8{0, 3, 5, 6, 7, 10}},
+}
+
+// Scales returns the names of all available scales.
+func Scales() []string {
+	var names []string
+	for _, s := range scales {
+		names = append(names, s.name)
+	}
+	return names
+}
+
+// scaleNotes returns all the notes of the given scale between the min and max
+// notes. If the scale isn't found, we use the chromatic one.
+func scaleNotes(name string, root, minNote, maxNote uint8) []uint8 {
+	intervals := scales[0].intervals
+	for _, s := range scales {
+		if s.name == name {
+			intervals = s.intervals
+		}
+	}
+
+	var notes []uint8
+	for note := int(minNote); note <= int(maxNote); note++ {
+		for _, interval := range intervals {
+			if mod(note-int(root)-int(interval), 12) == 0 {
+				notes = append(notes, uint8(note))
+				break
+			}
+		}
+	}
+	return notes
+}
@@ -11,10 +11,13 @@
 package sequencer
 
 import (
+	"fmt"
 	"math/rand"
+	"sync"
 	"time"
 
 	"sektron/filesystem"
+	"sektron/link"
 	"sektron/midi"
 )
 
@@ -33,7 +36,13 @@ const (
 )
 
 // Sequencer contains the sequencer state.
+//
+// The sequencer isn't safe for concurrent use: the clock, the tracks and the
+// midi inputs goroutines update it while holding its lock, and so must the
+// uis and servers calling it from their own goroutine (check Lock).
 type Sequencer interface {
+	Lock()
+	Unlock()
 	TogglePlay()
 	IsPlaying() bool
 	Save()
@@ -43,7 +52,11 @@ type Sequencer interface {
 	ChainNow(pattern int)
 	FullChain() []int
 	Patterns() []filesystem.Pattern
+	Pattern(pattern int) filesystem.Pattern
+	SetPattern(pattern int, p filesystem.Pattern) error
 	ActivePattern() int
+	Export(filename string, patterns []int, seed int64) error
+	Import(filename string, pattern int) error
 	AddTrack()
 	RemoveTrack()
 	Tracks() []*track
@@ -53,48 +66,144 @@ type Sequencer interface {
 	ToggleStep(track, step int)
 	CopyStep(track, step int)
 	PasteStep(track, step int)
+	CopySteps(track, first, last int)
+	PasteSteps(track, first int)
+	ClearSteps(track, first, last int)
+	CopyTrack(track int)
+	PasteTrack(track int)
+	ClearTrack(track int)
+	CopyPattern(pattern int)
+	PastePattern(pattern int)
+	ClearPattern(pattern int)
+	RotateTrack(track, steps int)
+	ReverseTrack(track int)
+	InvertTrack(track int)
+	DoubleTrack(track int)
+	HalveTrack(track int)
+	Randomize(track int, settings filesystem.Randomizer)
+	Edit(description string, edit func())
+	Undo() string
+	Redo() string
 	Tempo() float64
 	SetTempo(tempo float64)
+	TempoRamp() (filesystem.TempoRamp, bool)
+	SetTempoRamp(ramp filesystem.TempoRamp)
+	IsRamping() (float64, bool)
+	TapTempo() bool
+	Nudge(direction int)
+	OutputDevices() []string
+	SetClockOutputs(outputs []filesystem.ClockOutput)
+	SyncTo(input int) error
+	EnableLink() error
+	DisableLink()
+	LinkPeers() (int, bool)
+	EnableThru(settings filesystem.Thru) error
+	DisableThru()
+	SetThruTrack(track int)
+	EnableRemote(settings filesystem.Remote) error
+	DisableRemote()
+	SetMetronome(settings filesystem.Metronome)
+	CountIn() (int, bool)
+	ExternalTempo() (float64, bool)
+	ClockStats() ClockStats
+	OnTrigger(f func(track int, step Step)) func()
 	Reset()
 }
 
 type sequencer struct {
+	// mu serializes the access to the sequencer state (check Lock).
+	mu sync.Mutex
+
+	// triggers waits for each track to be triggered on each pulse, so that
+	// they are one at a time while the lock is held (check tick).
+	triggers sync.WaitGroup
+
 	midi  midi.Midi
 	bank  filesystem.Bank
 	chain []int
 
+	// The tracks send their notes and controls to the output, which routes
+	// them to either a midi device or another backend. The clock and the
+	// metronome are sent to the midi devices only.
+	output midi.Output
+
 	randomizer *rand.Rand
 
 	tracks []*track
 	clock  *clock
 
-	// Holds the midi devices to which we should send the clock.
-	clockSend []int
+	// Holds the midi devices to which we should send the clock and the
+	// transport messages (check transport.go), and the time played since
+	// the beginning of the song, sent as MIDI Time Code (check timecode.go).
+	clockSend []clockOutput
+	songTime  time.Duration
 
 	isPlaying bool
 
 	isFirstTick bool
 
+	// Holds the active pattern tempo ramp and the tempo it starts from
+	// (check ramp.go).
+	ramp      *filesystem.TempoRamp
+	rampStart float64
+
+	// Holds the external clock state when synced to one (check sync.go).
+	external *externalClock
+
+	// Holds the Ableton Link session when linked (check link.go). While
+	// waiting for the next session bar to start playing, waitForLink is set.
+	link        link.Link
+	waitForLink bool
+
+	// Holds the midi thru state when enabled (check thru.go), and the func
+	// that stops listening to the remote control (check remote.go).
+	thru       *thru
+	stopRemote func()
+
+	// Holds the metronome state and the number of pulses left to count in
+	// before playing (check metronome.go).
+	metronome metronome
+	countIn   int
+
+	// Holds the last tap tempo timestamps (check tempo.go).
+	taps []time.Time
+
 	stepClipboard step
+	clipboard     clipboard
+
+	// Holds the undo and redo stacks (check history.go).
+	history history
+
+	// Holds the funcs called when a step is triggered, by id (check
+	// OnTrigger).
+	triggerHooks  map[int]func(track int, step Step)
+	nextTriggerID int
 }
 
 // New creates a new sequencer. It also creates new tracks and calls the
-// start() method that starts the clock.
-func New(midi midi.Midi, bank filesystem.Bank) Sequencer {
+// start() method that starts the clock. The track devices are the ones of
+// the given output.
+func New(midi midi.Midi, output midi.Output, bank filesystem.Bank) Sequencer {
 	// The randomizer will be used for step trigger probability.
 	// Check step.go.
 	r := rand.New(rand.NewSource(time.Now().UnixNano()))
 
 	seq := &sequencer{
-		midi:        midi,
-		bank:        bank,
-		randomizer:  r,
-		clockSend:   []int{defaultDevice},
+		midi:       midi,
+		output:     output,
+		bank:       bank,
+		randomizer: r,
+		clockSend: []clockOutput{
+			{device: defaultDevice, clock: true, transport: true, divider: 1, multiplier: 1},
+		},
+		rampStart:   defaultTempo,
 		isPlaying:   false,
 		isFirstTick: false,
 	}
 
 	// Let's start the clock right away.
+	seq.mu.Lock()
+	defer seq.mu.Unlock()
 	seq.start()
 
 	// Load the last active pattern from bank if available.
@@ -104,16 +213,77 @@ func New(midi midi.Midi, bank filesystem.Bank) Sequencer {
 	return seq
 }
 
+// Lock locks the sequencer state. It must be held while calling the other
+// methods from another goroutine than the one of the clock, the tracks or
+// the midi inputs, including the methods of the tracks and steps.
+func (s *sequencer) Lock() {
+	s.mu.Lock()
+}
+
+// Unlock unlocks the sequencer state.
+func (s *sequencer) Unlock() {
+	s.mu.Unlock()
+}
+
+// unlocked calls f with the lock released. It's used to stop listening to
+// the midi inputs: the driver waits for the running callback to return,
+// while the callback may be waiting for the lock.
+func (s *sequencer) unlocked(f func()) {
+	s.mu.Unlock()
+	defer s.mu.Lock()
+	f()
+}
+
 // TogglePlay plays or stops the sequencer. When stopping, the sequencer resets
 // the playhead to the first step and stops all the playing notes.
 func (s *sequencer) TogglePlay() {
-	s.isPlaying = !s.isPlaying
-	if !s.isPlaying {
+	if s.isPlaying {
+		s.isPlaying = false
+		s.sendTransport(s.midi.SendStop)
+		s.stopClick()
 		s.Reset()
+		s.locate(0)
+		s.stopRamp()
+	} else {
+		s.play()
+	}
+}
+
+// play starts playing from the current playhead position. Devices receive a
+// Start message if we play from the beginning (after the count-in), a
+// Continue message otherwise.
+func (s *sequencer) play() {
+	if s.isAtStart() {
+		s.waitForLink = s.link != nil
+		if !s.startCountIn() {
+			s.sendStart()
+		}
 	} else {
-		s.isFirstTick = true
-		s.sendControls()
+		s.sendTransport(s.midi.SendContinue)
+	}
+	s.isPlaying = true
+	s.isFirstTick = true
+	s.sendControls()
+	s.startRamp()
+}
+
+// sendStart sends a Start message to the devices.
+func (s *sequencer) sendStart() {
+	s.resetClockOutputs()
+	s.sendTransport(s.midi.SendStart)
+	s.locate(0)
+}
+
+// pause stops playing and the playing notes, but keeps the playhead
+// position.
+func (s *sequencer) pause() {
+	s.isPlaying = false
+	s.sendTransport(s.midi.SendStop)
+	s.stopClick()
+	for _, track := range s.tracks {
+		track.clear()
 	}
+	s.stopRamp()
 }
 
 // IsPlaying returns the sequencer playing status.
@@ -127,13 +297,19 @@ func (s *sequencer) AddTrack() {
 	if len(s.tracks) == maxTracks {
 		return
 	}
+	s.record("add track")
+	s.addTrack()
+}
+
+// addTrack adds a new track without recording it in the undo history.
+func (s *sequencer) addTrack() {
 	pulse := 0
 	if len(s.tracks) > 0 {
 		pulse = s.tracks[0].pulse
 	}
 	channel := len(s.tracks)
 	track := &track{
-		midi:                  s.midi,
+		output:                s.output,
 		seq:                   s,
 		pulse:                 pulse,
 		chord:                 []uint8{defaultNote},
@@ -146,13 +322,13 @@ func (s *sequencer) AddTrack() {
 		lastSentControlValues: map[int]int16{},
 		active:                true,
 	}
-	track.controls = midi.NewControls(s.midi, track)
+	track.controls = midi.NewControls(s.output, track)
 
 	var steps []*step
 	for j := 0; j < defaultStepsPerTrack; j++ {
 		step := &step{
 			position: j,
-			midi:     s.midi,
+			output:   s.output,
 			track:    track,
 			active:   false,
 			controls: map[int]*midi.Control{},
@@ -171,6 +347,7 @@ func (s *sequencer) RemoveTrack() {
 	if len(s.tracks) == minTracks {
 		return
 	}
+	s.record("remove track")
 	s.tracks[len(s.tracks)-1].close()
 	s.tracks = s.tracks[:len(s.tracks)-1]
 }
@@ -187,11 +364,12 @@ func (s *sequencer) AddStep(track int) {
 	if len(t.steps) == maxSteps {
 		return
 	}
+	s.record(fmt.Sprintf("add step to track %d", track+1))
 	t.steps = append(
 		t.steps,
 		&step{
 			position: len(t.steps),
-			midi:     s.midi,
+			output:   s.output,
 			track:    t,
 			active:   false,
 		},
@@ -205,6 +383,7 @@ func (s *sequencer) RemoveStep(track int) {
 	if len(t.steps) == minSteps {
 		return
 	}
+	s.record(fmt.Sprintf("remove step from track %d", track+1))
 	if t.lastTriggeredStep == len(t.steps)-1 {
 		t.lastTriggeredStep = 0
 	}
@@ -220,9 +399,25 @@ func (s *sequencer) Tempo() float64 {
 	return s.clock.tempo
 }
 
-// SetTempo allows to set the clock to a new tempo.
+// SetTempo allows to set the clock to a new tempo. If the pattern has a tempo
+// ramp, it stops it and the ramp will start from the new tempo. When linked,
+// the new tempo is shared with the other peers.
 func (s *sequencer) SetTempo(tempo float64) {
+	if tempo > tempoMax || tempo < tempoMin {
+		return
+	}
+	if s.link != nil {
+		s.link.SetTempo(tempo)
+		s.alignToLink()
+		return
+	}
 	s.clock.setTempo(tempo)
+	s.rampStart = tempo
+}
+
+// ClockStats returns the clock timing statistics since the sequencer started.
+func (s *sequencer) ClockStats() ClockStats {
+	return s.clock.stats.get()
 }
 
 // Reset resets all sequencer tracks (check track.go)
@@ -237,6 +432,7 @@ func (s *sequencer) ToggleTrack(track int) {
 	if len(s.tracks) <= track {
 		return
 	}
+	s.record(fmt.Sprintf("toggle track %d", track+1))
 	s.tracks[track].active = !s.tracks[track].active
 }
 
@@ -245,6 +441,7 @@ func (s *sequencer) ToggleStep(track, step int) {
 	if len(s.tracks[track].steps) <= step {
 		return
 	}
+	s.record(fmt.Sprintf("toggle step %d of track %d", step+1, track+1))
 	s.tracks[track].steps[step].active = !s.tracks[track].steps[step].active
 	s.tracks[track].steps[step].clearParameters()
 }
@@ -259,7 +456,7 @@ func (s *sequencer) CopyStep(track, srcStep int) {
 
 	// Create a deep copy of the step
 	s.stepClipboard = step{
-		midi:        originalStep.midi,
+		output:      originalStep.output,
 		track:       nil, // We don't want to keep a reference to the original track
 		position:    originalStep.position,
 		active:      originalStep.active,
@@ -285,9 +482,11 @@ func (s *sequencer) PasteStep(track, dstStep int) {
 		return // Out of bounds, do nothing
 	}
 
+	s.record(fmt.Sprintf("paste step %d of track %d", dstStep+1, track+1))
+
 	// Create a deep copy of the clipboard
 	newStep := step{
-		midi:        s.stepClipboard.midi,
+		output:      s.stepClipboard.output,
 		track:       s.tracks[track],
 		position:    dstStep,
 		active:      s.stepClipboard.active,
@@ -313,34 +512,85 @@ func (s *sequencer) PasteStep(track, dstStep int) {
 func (s *sequencer) start() {
 	// Each time the clock ticks, we call the sequencer tick method that
 	// basically makes every track move forward in time.
-	s.clock = newClock(defaultTempo, func() {
+	// When synced to an external clock, its pulses drive the sequencer
+	// instead and we only check that it's still running.
+	s.clock = newClock(defaultTempo, &s.mu, func() {
+		if external := s.external; external != nil {
+			s.checkExternalClock(external)
+			return
+		}
 		s.tick()
 	})
 }
 
 func (s *sequencer) tick() {
-	// We send clock tick to the midi devices.
-	// TODO: make it configurable
-	s.midi.SendClock(s.clockSend)
+	// We send clock tick to the midi devices (check transport.go).
+	s.sendClock()
 
 	if !s.isPlaying {
 		return
 	}
 
+	// When linked, we wait for the next session bar to start playing.
+	if s.waitForLink {
+		if !s.isOnLinkBar() {
+			return
+		}
+		s.waitForLink = false
+	}
+
+	s.tickMetronome()
+
+	// While counting in, the tracks don't move forward.
+	if s.countIn > 0 {
+		s.countIn--
+		if s.countIn == 0 {
+			s.sendStart()
+		}
+		return
+	}
+
 	// Load first pattern in chain if chain not empty.
 	if !s.isFirstTick && s.tracks[0].pulse == 0 {
 		s.LoadNextInChain()
 	}
 
+	s.sendTimecode()
 	for _, track := range s.tracks {
+		s.triggers.Add(1)
 		track.tick()
+		s.triggers.Wait()
 	}
 
 	s.isFirstTick = false
 }
 
+// OnTrigger calls f each time a step is triggered, i.e. its notes are sent,
+// with its track index. The steps skipped because of their probability
+// aren't reported. f is called from the track goroutine with the lock held:
+// it mustn't block nor update the sequencer. It returns a func that stops
+// calling f, to call with the lock held too.
+func (s *sequencer) OnTrigger(f func(track int, step Step)) func() {
+	if s.triggerHooks == nil {
+		s.triggerHooks = map[int]func(track int, step Step){}
+	}
+	id := s.nextTriggerID
+	s.nextTriggerID++
+	s.triggerHooks[id] = f
+	return func() {
+		delete(s.triggerHooks, id)
+	}
+}
+
+// triggered calls the trigger hooks for the given step.
+func (s *sequencer) triggered(stp *step) {
+	for _, f := range s.triggerHooks {
+		f(stp.track.index(), stp)
+	}
+}
+
 // sendControls sends all track's active midi control messages.
-func (s sequencer) sendControls() {
+func (s *sequencer) sendControls() {
 	for _, track := range s.tracks {
 		track.sendControls()
 	}
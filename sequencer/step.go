@@ -20,7 +20,7 @@ type Step interface {
 }
 
 type step struct {
-	midi     midi.Midi
+	output   midi.Output
 	track    *track
 	position int
 
@@ -164,6 +164,7 @@ func (s step) OffsetString() string {
 
 // SetControl sets the given midi control.
 func (s *step) SetControl(nb int, value int16) {
+	s.recordEdit(s.track.controls[nb].Name())
 	_, ok := s.controls[nb]
 	if !ok {
 		control := s.track.controls[nb]
@@ -181,12 +182,13 @@ func (s *step) SetChord(chord []uint8) {
 		if s.track.seq.isPlaying {
 			continue
 		}
-		go func(note uint8) {
-			s.midi.NoteOn(s.track.device, s.track.channel, note, s.Velocity())
+		go func(device int, channel, note, velocity uint8) {
+			s.output.NoteOn(device, channel, note, velocity)
 			time.Sleep(time.Second)
-			s.midi.NoteOff(s.track.device, s.track.channel, note)
-		}(note)
+			s.output.NoteOff(device, channel, note)
+		}(s.track.device, s.track.channel, note, s.Velocity())
 	}
+	s.recordEdit("note")
 	s.reset()
 	s.chord = &chord
 }
@@ -196,6 +198,7 @@ func (s *step) SetLength(length int) {
 	if length < minLength {
 		return
 	}
+	s.recordEdit("length")
 	// Infinite mode
 	if length > maxLength {
 		length = maxLength
@@ -210,6 +213,7 @@ func (s *step) SetVelocity(velocity uint8) {
 	if velocity < minVelocity || velocity > maxVelocity {
 		return
 	}
+	s.recordEdit("velocity")
 	s.velocity = &velocity
 }
 
@@ -218,6 +222,7 @@ func (s *step) SetProbability(probability int) {
 	if probability < minProbability || probability > maxProbability {
 		return
 	}
+	s.recordEdit("probability")
 	s.probability = &probability
 }
 
@@ -226,6 +231,7 @@ func (s *step) SetOffset(offset int) {
 	if offset < minOffset || offset > maxOffset {
 		return
 	}
+	s.recordEdit("offset")
 	s.offset = offset
 }
 
@@ -237,10 +243,15 @@ func (s *step) trigger() {
 	}
 	s.sendControls()
 	for _, note := range s.Chord() {
-		s.midi.NoteOn(s.track.device, s.track.channel, note, s.Velocity())
+		if output, ok := s.output.(midi.LengthOutput); ok {
+			output.NoteOnFor(s.track.device, s.track.channel, note, s.Velocity(), s.duration())
+			continue
+		}
+		s.output.NoteOn(s.track.device, s.track.channel, note, s.Velocity())
 	}
 	s.triggered = true
 	s.track.lastTriggeredStep = s.position
+	s.track.seq.triggered(s)
 }
 
 // sendControls sends midi control messages if there step value are
@@ -256,6 +267,21 @@ func (s step) sendControls() {
 	}
 }
 
+// recordEdit records a step parameter edit in the undo history.
+func (s *step) recordEdit(param string) {
+	s.track.seq.recordEdit(fmt.Sprintf("track %d step %d %s", s.track.index()+1, s.position+1, param))
+}
+
+// duration returns the time between the step note on and note off at the
+// current tempo, 0 if infinite. The note stops on the last pulse of its
+// length (check endingPulse).
+func (s step) duration() time.Duration {
+	if s.isInfinite() {
+		return 0
+	}
+	return time.Duration(s.Length()-1) * s.track.seq.pulseInterval()
+}
+
 func (s step) skip() bool {
 	return s.Probability() < 100 && s.track.seq.randomizer.Intn(100) > s.Probability()
 }
@@ -299,7 +325,7 @@ func (s *step) reset() {
 		return
 	}
 	for _, note := range s.Chord() {
-		s.midi.NoteOff(s.track.device, s.track.channel, note)
+		s.output.NoteOff(s.track.device, s.track.channel, note)
 	}
 	s.triggered = false
 }
@@ -0,0 +1,190 @@
+package sequencer
+
+import (
+	"sync"
+	"time"
+
+	"sektron/midi"
+)
+
+const (
+	// The external clock tempo is estimated over the last quarter note.
+	syncWindow int = pulsesPerStep * stepsPerQuarterNote
+
+	// If no pulse is received during this period while playing, we consider
+	// the external clock lost.
+	clockLossTimeout time.Duration = time.Second
+)
+
+// externalClock holds the state of the external midi clock the sequencer is
+// synced to. Pulses are received from the midi listening goroutine and read
+// from the ui, hence the mutex.
+// pausedByLoss is set when playing was paused because the clock was lost, so
+// that it resumes when the pulses come back.
+type externalClock struct {
+	mu           sync.Mutex
+	stop         func()
+	pulses       []time.Time
+	lost         bool
+	pausedByLoss bool
+}
+
+// pulse records a new pulse timestamp.
+func (e *externalClock) pulse(now time.Time) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.lost = false
+	e.pulses = append(e.pulses, now)
+	if len(e.pulses) > syncWindow+1 {
+		e.pulses = e.pulses[1:]
+	}
+}
+
+// tempo estimates the external clock tempo from the last received pulses.
+// It returns 0 if there are not enough pulses yet or the clock is lost.
+func (e *externalClock) tempo() float64 {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.lost || len(e.pulses) < 2 {
+		return 0
+	}
+	elapsed := e.pulses[len(e.pulses)-1].Sub(e.pulses[0])
+	if elapsed <= 0 {
+		return 0
+	}
+	pulses := float64(len(e.pulses) - 1)
+	return pulses * float64(time.Minute) / (float64(elapsed) * float64(pulsesPerStep*stepsPerQuarterNote))
+}
+
+// interval returns the duration between the last two received pulses.
+func (e *externalClock) interval() time.Duration {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if len(e.pulses) < 2 {
+		return 0
+	}
+	return e.pulses[len(e.pulses)-1].Sub(e.pulses[len(e.pulses)-2])
+}
+
+// setPausedByLoss sets whether playing is paused because the clock was lost.
+func (e *externalClock) setPausedByLoss(paused bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.pausedByLoss = paused
+}
+
+// shouldResume returns true if playing was paused because the clock was lost,
+// and resets it.
+func (e *externalClock) shouldResume() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	resume := e.pausedByLoss
+	e.pausedByLoss = false
+	return resume
+}
+
+// checkLoss marks the clock as lost if no pulse has been received for a
+// while. It returns true only when the loss is detected.
+func (e *externalClock) checkLoss(now time.Time) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.lost || len(e.pulses) == 0 || now.Sub(e.pulses[len(e.pulses)-1]) < clockLossTimeout {
+		return false
+	}
+	e.lost = true
+	e.pulses = nil
+	return true
+}
+
+// SyncTo makes the sequencer follow the midi clock received on the given
+// input device instead of its own clock. Use a negative input to go back to
+// the internal clock.
+func (s *sequencer) SyncTo(input int) error {
+	if external := s.external; external != nil {
+		s.external = nil
+		s.unlocked(external.stop)
+	}
+	if input < 0 {
+		return nil
+	}
+
+	external := &externalClock{}
+	stop, err := s.midi.ListenClock(input, func(msg midi.ClockMessage) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.receiveClock(external, msg)
+	})
+	if err != nil {
+		return err
+	}
+	external.stop = stop
+	s.external = external
+	return nil
+}
+
+// ExternalTempo returns the estimated tempo of the external clock, if the
+// sequencer is synced to one. The tempo is 0 while it can't be estimated,
+// e.g. when the clock is lost.
+func (s *sequencer) ExternalTempo() (float64, bool) {
+	external := s.external
+	if external == nil {
+		return 0, false
+	}
+	return external.tempo(), true
+}
+
+// receiveClock handles the messages of the external clock:
+//   - Timing Clock makes the sequencer move forward by one pulse. If playing
+//     was paused because the clock was lost, it resumes from the current
+//     position
+//   - Start plays from the beginning
+//   - Stop stops the playing notes and keeps the current position
+//   - Continue plays from the current position
+//   - Song Position Pointer moves the playhead
+func (s *sequencer) receiveClock(external *externalClock, msg midi.ClockMessage) {
+	switch msg.Type {
+	case midi.ClockPulse:
+		external.pulse(time.Now())
+		if external.shouldResume() && !s.isPlaying {
+			s.play()
+		}
+		s.tick()
+	case midi.ClockStart:
+		external.setPausedByLoss(false)
+		s.Reset()
+		s.play()
+	case midi.ClockContinue:
+		external.setPausedByLoss(false)
+		s.play()
+	case midi.ClockStop:
+		external.setPausedByLoss(false)
+		s.pause()
+	case midi.ClockPosition:
+		s.setPosition(int(msg.Position))
+	}
+}
+
+// checkExternalClock is called by the internal clock while synced to an
+// external one. If the external clock is lost while playing, we stop the
+// playing notes and wait for it to come back to resume playing.
+func (s *sequencer) checkExternalClock(external *externalClock) {
+	if external.checkLoss(time.Now()) && s.isPlaying {
+		s.pause()
+		external.setPausedByLoss(true)
+	}
+}
+
+// setPosition moves the playhead of every track to the given position, in
+// steps (16th notes) from the beginning of the song, and sends it to the
+// devices as a Song Position Pointer and a MIDI Time Code Full Frame.
+func (s *sequencer) setPosition(position int) {
+	for _, t := range s.tracks {
+		t.clear()
+		t.pulse = (position * pulsesPerStep) % (len(t.steps) * pulsesPerStep)
+	}
+	s.metronome.pulse = position * pulsesPerStep
+	s.sendTransport(func(devices []int) {
+		s.midi.SendSongPosition(devices, uint16(position))
+	})
+	s.locate(s.stepsDuration(position))
+}
@@ -0,0 +1,49 @@
+package sequencer
+
+import (
+	"math"
+	"time"
+)
+
+const (
+	// Tap tempo averages the intervals between the last taps. If the last tap
+	// is too old, we start over.
+	maxTaps    int           = 4
+	tapTimeout time.Duration = 2 * time.Second
+)
+
+// TapTempo registers a tap and sets the tempo from the average interval
+// between the last taps. It returns true if the tempo has been updated (at
+// least two taps are needed).
+func (s *sequencer) TapTempo() bool {
+	now := time.Now()
+	if len(s.taps) > 0 && now.Sub(s.taps[len(s.taps)-1]) > tapTimeout {
+		s.taps = nil
+	}
+	s.taps = append(s.taps, now)
+	if len(s.taps) > maxTaps {
+		s.taps = s.taps[len(s.taps)-maxTaps:]
+	}
+	if len(s.taps) < 2 {
+		return false
+	}
+
+	interval := s.taps[len(s.taps)-1].Sub(s.taps[0]) / time.Duration(len(s.taps)-1)
+	tempo := math.Round(float64(time.Minute)/float64(interval)*10) / 10
+	if tempo > tempoMax || tempo < tempoMin {
+		return false
+	}
+	s.SetTempo(tempo)
+	return true
+}
+
+// Nudge temporarily speeds up (positive direction) or slows down (negative
+// direction) the clock, without changing the tempo. Calling it repeatedly
+// (e.g. when a key is held) keeps the clock nudged.
+func (s *sequencer) Nudge(direction int) {
+	// When linked, nudging would move the clock away from the session.
+	if s.link != nil {
+		return
+	}
+	s.clock.setNudge(direction)
+}
@@ -0,0 +1,137 @@
+package sequencer
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+
+	"sektron/filesystem"
+	"sektron/midi"
+)
+
+// thru holds the midi thru state. The messages received on the inputs are
+// forwarded to the thru track device, on its channel.
+type thru struct {
+	stops    []func()
+	messages []string
+
+	// output and device are where the messages are sent: the tracks output
+	// when merged with the sequencer notes, or a dedicated midi device.
+	output midi.Output
+	device int
+
+	mu    sync.Mutex
+	track int
+
+	// notes holds where the playing notes were sent, by input channel and
+	// note, so that they stop on the same device and channel even if the
+	// thru track changed meanwhile.
+	notes map[[2]uint8]thruNote
+}
+
+type thruNote struct {
+	device  int
+	channel uint8
+}
+
+// EnableThru forwards the messages of the given midi inputs to the thru
+// track (check SetThruTrack), as set in the given settings.
+func (s *sequencer) EnableThru(settings filesystem.Thru) error {
+	s.DisableThru()
+
+	t := &thru{
+		messages: settings.Messages,
+		output:   s.output,
+		device:   -1,
+		notes:    map[[2]uint8]thruNote{},
+	}
+	if settings.Output != "" {
+		t.output = s.midi
+		t.device = slices.Index(s.midi.Names(), settings.Output)
+		if t.device < 0 {
+			return fmt.Errorf("thru midi output %q not found", settings.Output)
+		}
+	}
+	for _, name := range settings.Inputs {
+		input := s.inputIndex(name)
+		if input < 0 {
+			s.unlocked(t.stop)
+			return fmt.Errorf("thru midi input %q not found", name)
+		}
+		stop, err := s.midi.ListenThru(input, func(msg midi.ThruMessage) {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			s.forward(t, msg)
+		})
+		if err != nil {
+			s.unlocked(t.stop)
+			return err
+		}
+		t.stops = append(t.stops, stop)
+	}
+	s.thru = t
+	return nil
+}
+
+// DisableThru stops forwarding the midi inputs messages.
+func (s *sequencer) DisableThru() {
+	t := s.thru
+	if t == nil {
+		return
+	}
+	s.thru = nil
+	s.unlocked(t.stop)
+}
+
+// SetThruTrack sets the track that receives the midi thru messages, usually
+// the one selected in the ui.
+func (s *sequencer) SetThruTrack(track int) {
+	t := s.thru
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.track = track
+}
+
+func (t *thru) stop() {
+	for _, stop := range t.stops {
+		stop()
+	}
+}
+
+// forward sends a message received on a thru input to the thru track device
+// and channel. It's called from the listening goroutines.
+func (s *sequencer) forward(t *thru, msg midi.ThruMessage) {
+	if len(t.messages) > 0 && !slices.Contains(t.messages, msg.Type.String()) {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	key := [2]uint8{msg.Channel, msg.Data1}
+	if msg.Type == midi.ThruNoteOff {
+		if note, ok := t.notes[key]; ok {
+			delete(t.notes, key)
+			msg.SendTo(t.output, note.device, note.channel)
+		}
+		return
+	}
+
+	tracks := s.tracks
+	if t.track >= len(tracks) {
+		return
+	}
+	device, channel := tracks[t.track].device, tracks[t.track].channel
+	if t.device >= 0 {
+		device = t.device
+	}
+	if msg.Type == midi.ThruNoteOn {
+		if note, ok := t.notes[key]; ok {
+			t.output.NoteOff(note.device, note.channel, msg.Data1)
+		}
+		t.notes[key] = thruNote{device: device, channel: channel}
+	}
+	msg.SendTo(t.output, device, channel)
+}
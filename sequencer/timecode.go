@@ -0,0 +1,77 @@
+package sequencer
+
+import (
+	"time"
+
+	"sektron/midi"
+)
+
+// quarterFramesPerFrame is the number of MIDI Time Code Quarter Frame
+// messages sent per frame. A whole time code takes 8 of them, i.e. 2 frames.
+const quarterFramesPerFrame = 4
+
+// sendTimecode sends the Quarter Frame messages of the current pulse to the
+// devices receiving the MIDI Time Code, evenly spread over the pulse
+// interval, then moves the song time forward. It's called on every pulse
+// while the tracks play.
+func (s *sequencer) sendTimecode() {
+	interval := s.pulseInterval()
+	start := s.songTime
+	s.songTime += interval
+	for i := range s.clockSend {
+		output := &s.clockSend[i]
+		if output.timecode == nil {
+			continue
+		}
+		quarter := output.timecode.FrameDuration() / quarterFramesPerFrame
+		for {
+			at := time.Duration(output.quarterFrame) * quarter
+			if at >= s.songTime {
+				break
+			}
+			// The time code of the 8 messages is the one of the frame the
+			// first one is sent on.
+			piece := output.quarterFrame % 8
+			frame := (output.quarterFrame - piece) / quarterFramesPerFrame
+			data := midi.NewTimeCode(frame, *output.timecode).QuarterFrame(piece)
+			devices := []int{output.device}
+			after(output.offset+max(at-start, 0), func() {
+				s.midi.SendQuarterFrame(devices, data)
+			})
+			output.quarterFrame++
+		}
+	}
+}
+
+// locate moves the song time to the given position, and sends it to the
+// devices receiving the MIDI Time Code as a Full Frame message. The Quarter
+// Frame messages start over from the next whole time code.
+func (s *sequencer) locate(position time.Duration) {
+	s.songTime = position
+	for i := range s.clockSend {
+		output := &s.clockSend[i]
+		if output.timecode == nil {
+			continue
+		}
+		output.quarterFrame = nextQuarterFrame(position, *output.timecode)
+		tc := midi.NewTimeCode(int(position/output.timecode.FrameDuration()), *output.timecode)
+		devices := []int{output.device}
+		after(output.offset, func() {
+			s.midi.SendFullFrame(devices, tc)
+		})
+	}
+}
+
+// nextQuarterFrame returns the first Quarter Frame message of the next whole
+// time code from the given position.
+func nextQuarterFrame(position time.Duration, rate midi.FrameRate) int {
+	quarter := rate.FrameDuration() / quarterFramesPerFrame
+	next := int((position + quarter - 1) / quarter)
+	return (next + 7) / 8 * 8
+}
+
+// stepsDuration returns the duration of the given number of steps at the
+// current tempo.
+func (s *sequencer) stepsDuration(steps int) time.Duration {
+	return time.Duration(float64(steps) / float64(stepsPerQuarterNote) / s.Tempo() * float64(time.Minute))
+}
@@ -25,8 +25,8 @@ type Track interface {
 }
 
 type track struct {
-	midi midi.Midi
-	seq  *sequencer
+	output midi.Output
+	seq    *sequencer
 
 	steps []*step
 
@@ -107,7 +107,7 @@ func (t track) Device() int {
 
 // DeviceString returns the device name string.
 func (t track) DeviceString() string {
-	return t.midi.Devices()[t.device].String()
+	return t.output.Names()[t.device]
 }
 
 // Channel returns the track midi channel.
@@ -178,9 +178,10 @@ func (t track) ProbabilityString() string {
 
 // SetDevice selects a device.
 func (t *track) SetDevice(device int) {
-	if device < 0 || len(t.midi.Devices()) <= device {
+	if device < 0 || len(t.output.Names()) <= device {
 		return
 	}
+	t.recordEdit("device")
 	t.clear()
 	t.device = device
 }
@@ -190,22 +191,26 @@ func (t *track) SetChannel(channel uint8) {
 	if channel < minChannel || channel > maxChannel {
 		return
 	}
+	t.recordEdit("channel")
 	t.clear()
 	t.channel = channel
 }
 
 // AddControl activayes a control.
 func (t *track) AddControl(nb int) {
+	t.seq.record(fmt.Sprintf("add %s to track %d", t.controls[nb].Name(), t.index()+1))
 	t.activeControls[nb] = struct{}{}
 }
 
 // RemoveControl desactivates a control.
 func (t *track) RemoveControl(nb int) {
+	t.seq.record(fmt.Sprintf("remove %s from track %d", t.controls[nb].Name(), t.index()+1))
 	delete(t.activeControls, nb)
 }
 
 // SetControl sets the given midi control.
 func (t *track) SetControl(nb int, value int16) {
+	t.recordEdit(t.controls[nb].Name())
 	t.controls[nb].Set(value)
 	t.controls[nb].Send()
 	t.lastSentControlValues[nb] = t.controls[nb].Value()
@@ -220,12 +225,13 @@ func (t *track) SetChord(chord []uint8) {
 		if t.seq.isPlaying {
 			continue
 		}
-		go func(note uint8) {
-			t.midi.NoteOn(t.device, t.channel, note, t.Velocity())
+		go func(device int, channel, note, velocity uint8) {
+			t.output.NoteOn(device, channel, note, velocity)
 			time.Sleep(time.Second)
-			t.midi.NoteOff(t.device, t.channel, note)
-		}(note)
+			t.output.NoteOff(device, channel, note)
+		}(t.device, t.channel, note, t.Velocity())
 	}
+	t.recordEdit("note")
 	t.clear()
 	t.chord = chord
 }
@@ -235,6 +241,7 @@ func (t *track) SetLength(length int) {
 	if length < minLength {
 		return
 	}
+	t.recordEdit("length")
 	// Infinite mode
 	if length > maxLength {
 		t.length = maxLength
@@ -248,6 +255,7 @@ func (t *track) SetVelocity(velocity uint8) {
 	if velocity < minVelocity || velocity > maxVelocity {
 		return
 	}
+	t.recordEdit("velocity")
 	t.velocity = velocity
 }
 
@@ -256,9 +264,25 @@ func (t *track) SetProbability(probability int) {
 	if probability < minProbability || probability > maxProbability {
 		return
 	}
+	t.recordEdit("probability")
 	t.probability = probability
 }
 
+// index returns the position of the track in the sequencer.
+func (t *track) index() int {
+	for i, track := range t.seq.tracks {
+		if track == t {
+			return i
+		}
+	}
+	return -1
+}
+
+// recordEdit records a track parameter edit in the undo history.
+func (t *track) recordEdit(param string) {
+	t.seq.recordEdit(fmt.Sprintf("track %d %s", t.index()+1, param))
+}
+
 func (t *track) start() {
 	t.trig = make(chan struct{})
 	t.done = make(chan struct{})
@@ -267,6 +291,7 @@ func (t *track) start() {
 			select {
 			case <-track.trig:
 				track.trigger()
+				track.seq.triggers.Done()
 			case <-track.done:
 				return
 			}
@@ -343,5 +368,5 @@ func (t *track) clear() {
 	for _, step := range t.steps {
 		step.reset()
 	}
-	t.midi.Silence(t.device, t.channel)
+	t.output.Silence(t.device, t.channel)
 }
@@ -0,0 +1,110 @@
+package sequencer
+
+import "fmt"
+
+// RotateTrack shifts all the steps of the given track by the given number of
+// steps, to the right if positive or to the left if negative. Steps keep their
+// parameters. Steps going beyond the end of the track come back at the
+// beginning.
+func (s *sequencer) RotateTrack(track, steps int) {
+	if track < 0 || track >= len(s.tracks) {
+		return
+	}
+	t := s.tracks[track]
+	length := len(t.steps)
+	if steps%length == 0 {
+		return
+	}
+	s.record(fmt.Sprintf("rotate track %d", track+1))
+	rotated := make([]*step, length)
+	for i, stp := range t.steps {
+		// The steps being played would stop relatively to their new
+		// position, so we stop them now.
+		stp.reset()
+		rotated[mod(i+steps, length)] = stp
+	}
+	t.lastTriggeredStep = mod(t.lastTriggeredStep+steps, length)
+	t.setSteps(rotated)
+}
+
+// ReverseTrack reverses the order of the steps of the given track.
+func (s *sequencer) ReverseTrack(track int) {
+	if track < 0 || track >= len(s.tracks) {
+		return
+	}
+	s.record(fmt.Sprintf("reverse track %d", track+1))
+	t := s.tracks[track]
+	length := len(t.steps)
+	reversed := make([]*step, length)
+	for i, stp := range t.steps {
+		// Like RotateTrack, we stop the steps being played.
+		stp.reset()
+		reversed[length-1-i] = stp
+	}
+	t.lastTriggeredStep = length - 1 - t.lastTriggeredStep
+	t.setSteps(reversed)
+}
+
+// InvertTrack activates all inactive steps of the given track and desactivates
+// the active ones. Steps parameters are kept.
+func (s *sequencer) InvertTrack(track int) {
+	if track < 0 || track >= len(s.tracks) {
+		return
+	}
+	s.record(fmt.Sprintf("invert track %d", track+1))
+	for _, stp := range s.tracks[track].steps {
+		if stp.active {
+			stp.reset()
+		}
+		stp.active = !stp.active
+	}
+}
+
+// DoubleTrack doubles the number of steps of the given track by duplicating
+// all its steps at the end of the track. Nothing happens if the track would
+// exceed the maximum number of steps.
+func (s *sequencer) DoubleTrack(track int) {
+	if track < 0 || track >= len(s.tracks) || len(s.tracks[track].steps)*2 > maxSteps {
+		return
+	}
+	s.record(fmt.Sprintf("double track %d", track+1))
+	t := s.tracks[track]
+	length := len(t.steps)
+	doubled := append([]*step{}, t.steps...)
+	for i, stp := range t.steps {
+		doubled = append(doubled, newStep(t, length+i, copyStep(stp.serialize())))
+	}
+	t.setSteps(doubled)
+}
+
+// HalveTrack removes the second half of the steps of the given track.
+func (s *sequencer) HalveTrack(track int) {
+	if track < 0 || track >= len(s.tracks) || len(s.tracks[track].steps)/2 < minSteps {
+		return
+	}
+	s.record(fmt.Sprintf("halve track %d", track+1))
+	t := s.tracks[track]
+	length := len(t.steps) / 2
+	// We stop the removed steps that are being played.
+	for _, stp := range t.steps[length:] {
+		stp.reset()
+	}
+	if t.lastTriggeredStep >= length {
+		t.lastTriggeredStep = 0
+	}
+	t.setSteps(t.steps[:length])
+}
+
+// setSteps replaces the track steps, updating their positions. If the
+// playhead is now beyond the last step, it goes back to the beginning.
+// The steps slice is replaced at once, as the track goroutine may be iterating
+// over it.
+func (t *track) setSteps(steps []*step) {
+	for i, stp := range steps {
+		stp.position = i
+	}
+	t.steps = steps
+	if t.pulse >= len(t.steps)*pulsesPerStep {
+		t.pulse %= len(t.steps) * pulsesPerStep
+	}
+}
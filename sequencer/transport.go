@@ -0,0 +1,159 @@
+package sequencer
+
+import (
+	"time"
+
+	"sektron/filesystem"
+	"sektron/midi"
+)
+
+// clockOutput holds which clock related messages are sent to a midi device
+// and how (check filesystem.ClockOutput).
+// The pulse counts the sequencer clock pulses since the last start, it's
+// used to divide or multiply the clock rate.
+// The timecode frame rate is set when the device receives the MIDI Time Code,
+// and quarterFrame holds the next Quarter Frame message to send (check
+// timecode.go).
+type clockOutput struct {
+	device       int
+	clock        bool
+	transport    bool
+	divider      int
+	multiplier   int
+	offset       time.Duration
+	pulse        int
+	timecode     *midi.FrameRate
+	quarterFrame int
+}
+
+// OutputDevices returns the names of the midi output devices.
+func (s *sequencer) OutputDevices() []string {
+	var devices []string
+	for _, device := range s.midi.Devices() {
+		devices = append(devices, device.String())
+	}
+	return devices
+}
+
+// SetClockOutputs sets the devices that receive clock related messages.
+// Devices that aren't connected are ignored. The devices that were already
+// receiving them keep their clock phase and time code position, so that the
+// settings can be changed while playing.
+func (s *sequencer) SetClockOutputs(outputs []filesystem.ClockOutput) {
+	devices := s.OutputDevices()
+	previous := map[int]clockOutput{}
+	for _, output := range s.clockSend {
+		previous[output.device] = output
+	}
+	var clockSend []clockOutput
+	for _, output := range outputs {
+		for i, device := range devices {
+			if device != output.Device {
+				continue
+			}
+			send := clockOutput{
+				device:     i,
+				clock:      output.Clock,
+				transport:  output.Transport,
+				divider:    max(output.Divider, 1),
+				multiplier: max(output.Multiplier, 1),
+				offset:     time.Duration(max(output.Offset, 0)) * time.Millisecond,
+			}
+			prev, ok := previous[i]
+			if ok {
+				send.pulse = prev.pulse
+			}
+			// The time code is disabled if the frame rate is invalid.
+			if rate, err := midi.ParseFrameRate(output.TimeCode); err == nil {
+				send.timecode = &rate
+				send.quarterFrame = nextQuarterFrame(s.songTime, rate)
+				if ok && prev.timecode != nil && *prev.timecode == rate {
+					send.quarterFrame = prev.quarterFrame
+				}
+			}
+			clockSend = append(clockSend, send)
+			break
+		}
+	}
+	s.clockSend = clockSend
+}
+
+// sendClock sends the Timing Clock messages of the current pulse to the
+// devices. A device clock rate can be divided, in which case we skip some
+// pulses, or multiplied, in which case the clock messages are evenly spread
+// over the pulse interval.
+func (s *sequencer) sendClock() {
+	interval := s.pulseInterval()
+	for i := range s.clockSend {
+		output := &s.clockSend[i]
+		if !output.clock {
+			continue
+		}
+		// Clock message n is sent at pulse n * divider / multiplier.
+		k, d := output.multiplier, output.divider
+		first := ceilDiv(output.pulse*k, d)
+		last := ceilDiv((output.pulse+1)*k, d)
+		for n := first; n < last; n++ {
+			fraction := float64(n*d-output.pulse*k) / float64(k)
+			delay := output.offset + time.Duration(fraction*float64(interval))
+			devices := []int{output.device}
+			after(delay, func() {
+				s.midi.SendClock(devices)
+			})
+		}
+		output.pulse++
+	}
+}
+
+// sendTransport sends a transport message to the devices, after their offset.
+func (s *sequencer) sendTransport(send func(devices []int)) {
+	for _, output := range s.clockSend {
+		if !output.transport {
+			continue
+		}
+		devices := []int{output.device}
+		after(output.offset, func() {
+			send(devices)
+		})
+	}
+}
+
+// resetClockOutputs restarts the clock outputs pulse count, so that divided
+// clocks are aligned with the Start message.
+func (s *sequencer) resetClockOutputs() {
+	for i := range s.clockSend {
+		s.clockSend[i].pulse = 0
+	}
+}
+
+// pulseInterval returns the current duration between two clock pulses.
+func (s *sequencer) pulseInterval() time.Duration {
+	if external := s.external; external != nil {
+		return external.interval()
+	}
+	return time.Duration(s.clock.interval())
+}
+
+// isAtStart returns true if all the tracks playheads are on their first
+// pulse.
+func (s *sequencer) isAtStart() bool {
+	for _, t := range s.tracks {
+		if t.pulse != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// after calls f after the given delay, or right away if there's no delay.
+func after(delay time.Duration, f func()) {
+	if delay <= 0 {
+		f()
+		return
+	}
+	time.AfterFunc(delay, f)
+}
+
+func ceilDiv(a, b int) int {
+	return (a + b - 1) / b
+}
@@ -21,6 +21,27 @@ type keyMap struct {
 	RemoveStep key.Binding
 	CopyStep   key.Binding
 	PasteStep  key.Binding
+	ClearStep  key.Binding
+
+	CopyTrack  key.Binding
+	PasteTrack key.Binding
+	ClearTrack key.Binding
+
+	RotateLeft  key.Binding
+	RotateRight key.Binding
+	Reverse     key.Binding
+	Invert      key.Binding
+	Double      key.Binding
+	Halve       key.Binding
+	Randomize   key.Binding
+
+	SelectRange  key.Binding
+	SelectEvery  key.Binding
+	SelectActive key.Binding
+	EditMode     key.Binding
+
+	Undo key.Binding
+	Redo key.Binding
 
 	NextStep     key.Binding
 	PreviousStep key.Binding
@@ -40,8 +61,18 @@ type keyMap struct {
 	PageUp   key.Binding
 	PageDown key.Binding
 
-	TempoUp   key.Binding
-	TempoDown key.Binding
+	TempoUp       key.Binding
+	TempoDown     key.Binding
+	FineTempoUp   key.Binding
+	FineTempoDown key.Binding
+	TapTempo      key.Binding
+	NudgeUp       key.Binding
+	NudgeDown     key.Binding
+	TempoEntry    key.Binding
+	ClockStats    key.Binding
+	Settings      key.Binding
+	MidiLearn     key.Binding
+	Export        key.Binding
 
 	AddParam    key.Binding
 	RemoveParam key.Binding
@@ -66,8 +97,11 @@ func (k keyMap) ShortHelp() []key.Binding {
 // key.Map interface.
 func (k keyMap) FullHelp() [][]key.Binding {
 	return [][]key.Binding{
-		{k.Play, k.ParamMode, k.PatternMode, k.AddTrack, k.RemoveTrack, k.AddStep, k.RemoveStep, k.PreviousStep, k.NextStep, k.TempoUp, k.TempoDown},
-		{k.Step, k.StepToggle, k.Track, k.TrackToggle, k.PageUp, k.PageDown, k.AddParam, k.RemoveParam},
+		{k.Play, k.ParamMode, k.PatternMode, k.AddTrack, k.RemoveTrack, k.AddStep, k.RemoveStep, k.PreviousStep, k.NextStep},
+		{k.TempoUp, k.TempoDown, k.FineTempoUp, k.FineTempoDown, k.TapTempo, k.NudgeUp, k.NudgeDown, k.TempoEntry, k.ClockStats, k.Settings, k.MidiLearn, k.Export},
+		{k.Step, k.StepToggle, k.Track, k.TrackToggle, k.PageUp, k.PageDown, k.AddParam, k.RemoveParam, k.SelectRange, k.SelectEvery, k.SelectActive, k.EditMode},
+		{k.CopyStep, k.PasteStep, k.ClearStep, k.CopyTrack, k.PasteTrack, k.ClearTrack, k.Undo, k.Redo},
+		{k.RotateLeft, k.RotateRight, k.Reverse, k.Invert, k.Double, k.Halve, k.Randomize},
 		{k.Validate, k.Up, k.Down, k.Left, k.Right, k.Help, k.Quit},
 	}
 }
@@ -105,11 +139,79 @@ func newKeyMap(keys filesystem.KeyMap) keyMap {
 		),
 		CopyStep: key.NewBinding(
 			key.WithKeys(keys.CopyStep),
-			key.WithHelp(keys.CopyStep, "copy active step parameters"),
+			key.WithHelp(keys.CopyStep, "copy active step|page|pattern"),
 		),
 		PasteStep: key.NewBinding(
 			key.WithKeys(keys.PasteStep),
-			key.WithHelp(keys.PasteStep, "paste copied parameters into active step"),
+			key.WithHelp(keys.PasteStep, "paste into active step|page|pattern"),
+		),
+		ClearStep: key.NewBinding(
+			key.WithKeys(keys.ClearStep),
+			key.WithHelp(keys.ClearStep, "clear active step|page|pattern"),
+		),
+		CopyTrack: key.NewBinding(
+			key.WithKeys(keys.CopyTrack),
+			key.WithHelp(keys.CopyTrack, "copy active track"),
+		),
+		PasteTrack: key.NewBinding(
+			key.WithKeys(keys.PasteTrack),
+			key.WithHelp(keys.PasteTrack, "paste into active track"),
+		),
+		ClearTrack: key.NewBinding(
+			key.WithKeys(keys.ClearTrack),
+			key.WithHelp(keys.ClearTrack, "clear active track steps"),
+		),
+		RotateLeft: key.NewBinding(
+			key.WithKeys(keys.RotateLeft),
+			key.WithHelp(keys.RotateLeft, "rotate active track steps left"),
+		),
+		RotateRight: key.NewBinding(
+			key.WithKeys(keys.RotateRight),
+			key.WithHelp(keys.RotateRight, "rotate active track steps right"),
+		),
+		Reverse: key.NewBinding(
+			key.WithKeys(keys.Reverse),
+			key.WithHelp(keys.Reverse, "reverse active track steps"),
+		),
+		Invert: key.NewBinding(
+			key.WithKeys(keys.Invert),
+			key.WithHelp(keys.Invert, "invert active track steps"),
+		),
+		Double: key.NewBinding(
+			key.WithKeys(keys.Double),
+			key.WithHelp(keys.Double, "double active track length"),
+		),
+		Halve: key.NewBinding(
+			key.WithKeys(keys.Halve),
+			key.WithHelp(keys.Halve, "halve active track length"),
+		),
+		Randomize: key.NewBinding(
+			key.WithKeys(keys.Randomize),
+			key.WithHelp(keys.Randomize, "randomizer mode|randomize with a new seed"),
+		),
+		SelectRange: key.NewBinding(
+			key.WithKeys(keys.SelectRange),
+			key.WithHelp(keys.SelectRange, "select steps range from active step"),
+		),
+		SelectEvery: key.NewBinding(
+			key.WithKeys(keys.SelectEvery),
+			key.WithHelp(keys.SelectEvery, "select every 2nd, 3rd... step from active step"),
+		),
+		SelectActive: key.NewBinding(
+			key.WithKeys(keys.SelectActive),
+			key.WithHelp(keys.SelectActive, "select all active steps"),
+		),
+		EditMode: key.NewBinding(
+			key.WithKeys(keys.EditMode),
+			key.WithHelp(keys.EditMode, "toggle relative|absolute selection edit"),
+		),
+		Undo: key.NewBinding(
+			key.WithKeys(keys.Undo),
+			key.WithHelp(keys.Undo, "undo last edit"),
+		),
+		Redo: key.NewBinding(
+			key.WithKeys(keys.Redo),
+			key.WithHelp(keys.Redo, "redo last undone edit"),
 		),
 		PreviousStep: key.NewBinding(
 			key.WithKeys(keys.PreviousStep),
@@ -155,6 +257,46 @@ func newKeyMap(keys filesystem.KeyMap) keyMap {
 			key.WithKeys(keys.TempoDown),
 			key.WithHelp(keys.TempoDown, "tempo down (1 bpm)"),
 		),
+		FineTempoUp: key.NewBinding(
+			key.WithKeys(keys.FineTempoUp),
+			key.WithHelp(keys.FineTempoUp, "tempo up (0.1 bpm)"),
+		),
+		FineTempoDown: key.NewBinding(
+			key.WithKeys(keys.FineTempoDown),
+			key.WithHelp(keys.FineTempoDown, "tempo down (0.1 bpm)"),
+		),
+		TapTempo: key.NewBinding(
+			key.WithKeys(keys.TapTempo),
+			key.WithHelp(keys.TapTempo, "tap tempo"),
+		),
+		NudgeUp: key.NewBinding(
+			key.WithKeys(keys.NudgeUp),
+			key.WithHelp(keys.NudgeUp, "nudge clock faster"),
+		),
+		NudgeDown: key.NewBinding(
+			key.WithKeys(keys.NudgeDown),
+			key.WithHelp(keys.NudgeDown, "nudge clock slower"),
+		),
+		TempoEntry: key.NewBinding(
+			key.WithKeys(keys.TempoEntry),
+			key.WithHelp(keys.TempoEntry, "type tempo"),
+		),
+		ClockStats: key.NewBinding(
+			key.WithKeys(keys.ClockStats),
+			key.WithHelp(keys.ClockStats, "show clock jitter"),
+		),
+		Settings: key.NewBinding(
+			key.WithKeys(keys.Settings),
+			key.WithHelp(keys.Settings, "clock settings"),
+		),
+		MidiLearn: key.NewBinding(
+			key.WithKeys(keys.MidiLearn),
+			key.WithHelp(keys.MidiLearn, "midi learn (controller to key or selected parameter)"),
+		),
+		Export: key.NewBinding(
+			key.WithKeys(keys.Export),
+			key.WithHelp(keys.Export, "export chain to midi file"),
+		),
 		AddParam: key.NewBinding(
 			key.WithKeys(keys.AddParam),
 			key.WithHelp(keys.AddParam, "add midi control"),
@@ -0,0 +1,266 @@
+package ui
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"sektron/filesystem"
+	"sektron/midi"
+	"sektron/sequencer"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const (
+	ccMessage   = "cc"
+	noteMessage = "note"
+
+	relativeMode = "relative"
+
+	playAction  = "play"
+	quitAction  = "quit"
+	learnAction = "midi_learn"
+)
+
+// ControllerMsg is sent to the ui when a midi controller sends a control
+// change or a note. Controller holds the input port name.
+type ControllerMsg struct {
+	Controller string
+	Message    midi.ControllerMessage
+}
+
+// learnState holds the midi learn mode state. Once a controller message is
+// received, the next key press binds it to the key action, or enter binds it
+// to the selected track or step parameter.
+type learnState struct {
+	active bool
+	msg    *ControllerMsg
+}
+
+func (m *mainModel) toggleLearn() {
+	m.learn = learnState{active: !m.learn.active}
+	if m.learn.active {
+		m.setStatus("midi learn: move a control or hit a pad")
+	} else {
+		m.setStatus("midi learn cancelled")
+	}
+}
+
+// learnKey binds the pending controller message to the given key action, or
+// to the selected parameter on enter.
+func (m *mainModel) learnKey(msg tea.KeyMsg) {
+	mapping := filesystem.MidiMapping{
+		Message: ccMessage,
+		Channel: m.learn.msg.Message.Channel,
+		Number:  m.learn.msg.Message.Number,
+	}
+	if m.learn.msg.Message.Type != midi.ControllerCC {
+		mapping.Message = noteMessage
+	}
+
+	if msg.String() == m.config.KeyMap.Validate && (m.mode == trackMode || m.mode == stepMode) {
+		if m.mode == trackMode {
+			mapping.Param = m.parameters.getTrackParam(m.getActiveParam()).name
+		} else {
+			mapping.Param = m.parameters.getStepParam(m.getActiveParam()).name
+		}
+	} else {
+		mapping.Action = keyAction(m.config.KeyMap, msg.String())
+		if mapping.Action == "" || mapping.Action == quitAction || mapping.Action == learnAction {
+			m.setStatus(fmt.Sprintf("midi learn: no action for %s", msg.String()))
+			return
+		}
+	}
+
+	controller := m.learn.msg.Controller
+	var mappings []filesystem.MidiMapping
+	for _, existing := range m.config.Controllers[controller] {
+		if existing.Message != mapping.Message || existing.Channel != mapping.Channel || existing.Number != mapping.Number {
+			mappings = append(mappings, existing)
+		}
+	}
+	if m.config.Controllers == nil {
+		m.config.Controllers = map[string][]filesystem.MidiMapping{}
+	}
+	m.config.Controllers[controller] = append(mappings, mapping)
+	m.config.Save()
+
+	target := mapping.Action
+	if mapping.Param != "" {
+		target = mapping.Param
+	}
+	m.setStatus(fmt.Sprintf("%s %d bound to %s", mapping.Message, mapping.Number, target))
+	m.learn = learnState{}
+}
+
+// receiveController handles a controller message: it's either learnt or
+// applied through its mapping.
+func (m mainModel) receiveController(msg ControllerMsg) (tea.Model, tea.Cmd) {
+	if m.learn.active {
+		if m.learn.msg == nil && msg.Message.Type != midi.ControllerNoteOff {
+			m.learn.msg = &msg
+			m.setStatus(fmt.Sprintf("midi learn: press a key, or enter for the selected parameter (%s)", msg.Controller))
+		}
+		return m, nil
+	}
+
+	for _, mapping := range m.config.Controllers[msg.Controller] {
+		if !matches(mapping, msg.Message) {
+			continue
+		}
+		if mapping.Param != "" {
+			m.setLearntParam(mapping, msg.Message.Value)
+			return m, nil
+		}
+		// Actions are triggered when buttons and pads are pressed only.
+		if msg.Message.Type == midi.ControllerNoteOff || msg.Message.Value == 0 {
+			return m, nil
+		}
+		if keyMsg, ok := newKeyMsg(actionKey(m.config.KeyMap, mapping.Action)); ok {
+			return m.update(keyMsg)
+		}
+	}
+	return m, nil
+}
+
+func matches(mapping filesystem.MidiMapping, msg midi.ControllerMessage) bool {
+	if mapping.Channel != msg.Channel || mapping.Number != msg.Number {
+		return false
+	}
+	if msg.Type == midi.ControllerCC {
+		return mapping.Message == ccMessage
+	}
+	return mapping.Message == noteMessage
+}
+
+// setLearntParam sets the mapped parameter of the active track, or of the
+// active step in step mode.
+func (m *mainModel) setLearntParam(mapping filesystem.MidiMapping, value uint8) {
+	switch m.mode {
+	case trackMode:
+		for _, p := range m.parameters.track {
+			if p.name == mapping.Param {
+				setFromController(&p, m.getActiveTrack(), mapping, value)
+			}
+		}
+	case stepMode:
+		if !m.getActiveStep().IsActive() {
+			return
+		}
+		for _, p := range m.parameters.step {
+			if p.name == mapping.Param {
+				setFromController(&p, m.getActiveStep(), mapping, value)
+			}
+		}
+	default:
+		return
+	}
+	m.stepModeTimer = 0
+	m.updateParams()
+}
+
+// setFromController sets the parameter value from a control value.
+func setFromController[t sequencer.Parametrable](p *parameter[t], item t, mapping filesystem.MidiMapping, value uint8) {
+	if mapping.Mode == relativeMode {
+		add := int(value)
+		if add >= 64 {
+			add -= 128
+		}
+		p.set(item, p.value(item), add)
+		return
+	}
+	min, max := paramRange(p.name)
+	p.set(item, min+int(value)*(max-min)/127, 0)
+}
+
+// paramRange returns the range the absolute control values are scaled to.
+func paramRange(name string) (int, int) {
+	switch name {
+	case "note":
+		return 21, 108
+	case "length":
+		return 2, pulsesPerStep*maxSteps + pulsesPerStep
+	case "probability":
+		return 0, 100
+	case "channel":
+		return 0, 15
+	case "offset":
+		return 0, 5
+	case controlName(midi.PitchbendControl):
+		return -8192, 8192
+	default:
+		return 0, 127
+	}
+}
+
+// keyActions returns the keymap action names and their keys. Entries with
+// multiple keys are named after their index, e.g. "steps.3" for the 3rd
+// step key.
+func keyActions(keys filesystem.KeyMap) map[string]string {
+	actions := map[string]string{playAction: " "}
+	content, _ := json.Marshal(keys)
+	var entries map[string]json.RawMessage
+	json.Unmarshal(content, &entries)
+	for name, raw := range entries {
+		var key string
+		if json.Unmarshal(raw, &key) == nil {
+			if key != "" {
+				actions[name] = key
+			}
+			continue
+		}
+		var multiple []string
+		if json.Unmarshal(raw, &multiple) == nil {
+			for i, key := range multiple {
+				actions[fmt.Sprintf("%s.%d", name, i+1)] = key
+			}
+		}
+	}
+	return actions
+}
+
+// keyAction returns the action name of the given key.
+func keyAction(keys filesystem.KeyMap, key string) string {
+	actions := keyActions(keys)
+	var names []string
+	for name, k := range actions {
+		if k == key {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return ""
+	}
+	// Keys can be shared, e.g. a step key selects a pattern in pattern mode.
+	sort.Strings(names)
+	return names[0]
+}
+
+// actionKey returns the key of the given action name.
+func actionKey(keys filesystem.KeyMap, action string) string {
+	return keyActions(keys)[action]
+}
+
+// newKeyMsg returns the key message matching a key binding string, the
+// reverse of tea.KeyMsg.String().
+func newKeyMsg(key string) (tea.KeyMsg, bool) {
+	if key == "" {
+		return tea.KeyMsg{}, false
+	}
+	for t := tea.KeyF20; t <= tea.KeyBackspace; t++ {
+		for _, alt := range []bool{false, true} {
+			msg := tea.KeyMsg{Type: t, Alt: alt}
+			if t != tea.KeyRunes && msg.String() == key {
+				return msg, true
+			}
+		}
+	}
+	alt := strings.HasPrefix(key, "alt+") && len(key) > len("alt+")
+	runes := []rune(strings.TrimPrefix(key, "alt+"))
+	if !alt {
+		runes = []rune(key)
+	}
+	return tea.KeyMsg{Type: tea.KeyRunes, Runes: runes, Alt: alt}, true
+}
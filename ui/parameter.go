@@ -3,6 +3,7 @@ package ui
 import (
 	"fmt"
 
+	"sektron/midi"
 	"sektron/sequencer"
 
 	"github.com/charmbracelet/bubbles/table"
@@ -64,6 +65,8 @@ func (p parameters) getParamIndex(nb int) int {
 }
 
 type parameter[t sequencer.Parametrable] struct {
+	// name identifies the parameter in the midi learn mappings.
+	name   string
 	value  func(item t) int
 	string func(item t) string
 	set    func(item t, value, add int)
@@ -72,6 +75,7 @@ type parameter[t sequencer.Parametrable] struct {
 
 func newMidiParameter[t sequencer.Parametrable](nb int) parameter[t] {
 	return parameter[t]{
+		name: controlName(nb),
 		value: func(item t) int {
 			return int(item.Control(nb).Value())
 		},
@@ -92,6 +96,22 @@ func newMidiParameter[t sequencer.Parametrable](nb int) parameter[t] {
 	}
 }
 
+// controlName returns the name of the given control in the midi learn
+// mappings: "program", "pitchbend", "aftertouch", or "cc" followed by the
+// controller number, e.g. "cc 74".
+func controlName(nb int) string {
+	switch nb {
+	case midi.ProgramControl:
+		return "program"
+	case midi.PitchbendControl:
+		return "pitchbend"
+	case midi.AfterTouchControl:
+		return "aftertouch"
+	default:
+		return fmt.Sprintf("cc %d", nb-midi.CCControl(0))
+	}
+}
+
 func (m *mainModel) initParameters() {
 	m.paramCarousel = carousel.New(
 		carousel.WithFocused(true),
@@ -100,6 +120,7 @@ func (m *mainModel) initParameters() {
 
 	m.parameters.track = []parameter[sequencer.Track]{
 		{
+			name: "note",
 			value: func(item sequencer.Track) int {
 				// TODO: make chords actual chords
 				return int(item.Chord()[0])
@@ -123,6 +144,7 @@ func (m *mainModel) initParameters() {
 			},
 		},
 		{
+			name: "length",
 			value: func(item sequencer.Track) int {
 				return item.Length()
 			},
@@ -143,6 +165,7 @@ func (m *mainModel) initParameters() {
 			},
 		},
 		{
+			name: "velocity",
 			value: func(item sequencer.Track) int {
 				return int(item.Velocity())
 			},
@@ -163,6 +186,7 @@ func (m *mainModel) initParameters() {
 			},
 		},
 		{
+			name: "probability",
 			value: func(item sequencer.Track) int {
 				return item.Probability()
 			},
@@ -183,6 +207,7 @@ func (m *mainModel) initParameters() {
 			},
 		},
 		{
+			name: "device",
 			value: func(item sequencer.Track) int {
 				return item.Device()
 			},
@@ -208,6 +233,7 @@ func (m *mainModel) initParameters() {
 			},
 		},
 		{
+			name: "channel",
 			value: func(item sequencer.Track) int {
 				return int(item.Channel())
 			},
@@ -237,6 +263,7 @@ func (m *mainModel) initParameters() {
 
 	m.parameters.step = []parameter[sequencer.Step]{
 		{
+			name: "note",
 			value: func(item sequencer.Step) int {
 				return int(item.Chord()[0])
 			},
@@ -259,6 +286,7 @@ func (m *mainModel) initParameters() {
 			},
 		},
 		{
+			name: "length",
 			value: func(item sequencer.Step) int {
 				return item.Length()
 			},
@@ -279,6 +307,7 @@ func (m *mainModel) initParameters() {
 			},
 		},
 		{
+			name: "velocity",
 			value: func(item sequencer.Step) int {
 				return int(item.Velocity())
 			},
@@ -299,6 +328,7 @@ func (m *mainModel) initParameters() {
 			},
 		},
 		{
+			name: "probability",
 			value: func(item sequencer.Step) int {
 				return item.Probability()
 			},
@@ -319,6 +349,7 @@ func (m *mainModel) initParameters() {
 			},
 		},
 		{
+			name: "offset",
 			value: func(item sequencer.Step) int {
 				return item.Offset()
 			},
@@ -380,6 +411,19 @@ func (p *parameter[t]) decrease(item t) {
 	p.set(item, p.value(item), -1)
 }
 
+// setAll adds the given value to the parameter of all items. In absolute
+// mode, all items get the reference item value plus the added value.
+func (p *parameter[t]) setAll(ref t, items []t, absolute bool, add int) {
+	value := p.value(ref)
+	for _, item := range items {
+		if absolute {
+			p.set(item, value, add)
+			continue
+		}
+		p.set(item, p.value(item), add)
+	}
+}
+
 func (m mainModel) renderParams() string {
 	left, right := "   ", "   "
 	if m.paramCarousel.HasLeftItems() {
@@ -400,12 +444,16 @@ func (m mainModel) renderParams() string {
 func (m *mainModel) updateParams() {
 	switch m.mode {
 	case stepMode:
+		subtitle := fmt.Sprintf("pattern %d", m.seq.ActivePattern()+1)
+		if m.selection.isActive() {
+			subtitle = m.selectionString()
+		}
 		m.parameters.title = paramStepTitleStyle.Render(
 			lipgloss.JoinVertical(
 				lipgloss.Center,
 				toASCIIFont(fmt.Sprintf("S%d", m.activeStep+1)),
 				"",
-				fmt.Sprintf("pattern %d", m.seq.ActivePattern()+1),
+				subtitle,
 			),
 		)
 	case trackMode, paramSelectMode:
@@ -417,6 +465,33 @@ func (m *mainModel) updateParams() {
 				fmt.Sprintf("pattern %d", m.seq.ActivePattern()+1),
 			),
 		)
+	case patternMode:
+		m.parameters.title = paramTrackTitleStyle.Render(
+			lipgloss.JoinVertical(
+				lipgloss.Center,
+				toASCIIFont(fmt.Sprintf("P%d", m.seq.ActivePattern()+1)),
+				"",
+				"tempo",
+			),
+		)
+	case randomizeMode:
+		m.parameters.title = paramTrackTitleStyle.Render(
+			lipgloss.JoinVertical(
+				lipgloss.Center,
+				toASCIIFont(fmt.Sprintf("T%d", m.activeTrack+1)),
+				"",
+				"randomizer",
+			),
+		)
+	case settingsMode:
+		m.parameters.title = paramTrackTitleStyle.Render(
+			lipgloss.JoinVertical(
+				lipgloss.Center,
+				toASCIIFont("SET"),
+				"",
+				"settings",
+			),
+		)
 	default:
 		m.parameters.title = ""
 	}
@@ -424,15 +499,16 @@ func (m *mainModel) updateParams() {
 	var params []string
 	m.parameters.index = map[int]int{}
 	if m.mode == stepMode {
-		if m.getActiveStep().IsActive() {
+		step := m.getReferenceStep()
+		if step.IsActive() {
 			for i, p := range m.parameters.step {
-				if !p.active(m.getActiveStep()) {
+				if !p.active(step) {
 					continue
 				}
 				m.parameters.index[len(params)] = i
 				params = append(
 					params,
-					p.string(m.getActiveStep()),
+					p.string(step),
 				)
 			}
 		}
@@ -447,6 +523,12 @@ func (m *mainModel) updateParams() {
 				p.string(m.getActiveTrack()),
 			)
 		}
+	} else if m.mode == randomizeMode {
+		params = m.randomizerParamsString()
+	} else if m.mode == settingsMode {
+		params = m.settingsParamsString()
+	} else if m.mode == patternMode {
+		params = m.patternParamsString()
 	} else if m.mode == paramSelectMode {
 		scrollIndicator := []string{
 			" ",
@@ -2,7 +2,11 @@ package ui
 
 import (
 	"fmt"
+	"path/filepath"
 	"strconv"
+	"strings"
+
+	"sektron/filesystem"
 
 	"github.com/charmbracelet/lipgloss"
 )
@@ -13,6 +17,7 @@ const (
 	patternsPerPage = 16
 	patternsPerLine = 8
 	patternPages    = 4
+	maxRampBars     = 64
 )
 
 var (
@@ -125,3 +130,57 @@ func (m mainModel) renderChain() string {
 			),
 		)
 }
+
+// patternParamsString returns the active pattern parameters to be displayed
+// in the parameters carousel.
+func (m mainModel) patternParamsString() []string {
+	ramp, ok := m.seq.TempoRamp()
+	if !ok {
+		return []string{
+			textParameter("off", "ramp bars"),
+			textParameter("-", "ramp to"),
+		}
+	}
+	return []string{
+		fontParameter(strconv.Itoa(ramp.Bars), "ramp bars"),
+		fontParameter(tempoString(ramp.Target), "ramp to"),
+	}
+}
+
+// setPatternParam increases or decreases the selected active pattern
+// parameter. Setting the ramp bars to 0 removes the tempo ramp.
+func (m *mainModel) setPatternParam(add int) {
+	ramp, ok := m.seq.TempoRamp()
+	if !ok {
+		ramp = filesystem.TempoRamp{Target: m.seq.Tempo()}
+	}
+	switch m.activePatternParam {
+	case 0:
+		ramp.Bars = clamp(ramp.Bars+add, 0, maxRampBars)
+	case 1:
+		if !ok {
+			return
+		}
+		ramp.Target += float64(add)
+	}
+	m.seq.SetTempoRamp(ramp)
+}
+
+// exportChain renders the active pattern and the chained ones to a Standard
+// MIDI File named after their numbers, e.g. sektron-1-2-2.mid.
+func (m *mainModel) exportChain() {
+	chain := m.seq.FullChain()
+	numbers := make([]string, len(chain))
+	for i, pattern := range chain {
+		numbers[i] = strconv.Itoa(pattern + 1)
+	}
+	filename := filepath.Join(
+		m.config.Export.Directory,
+		fmt.Sprintf("sektron-%s.mid", strings.Join(numbers, "-")),
+	)
+	if err := m.seq.Export(filename, chain, m.config.Export.Seed); err != nil {
+		m.setStatus(fmt.Sprintf("export failed: %s", err))
+		return
+	}
+	m.setStatus(fmt.Sprintf("exported to %s", filename))
+}
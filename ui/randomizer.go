@@ -0,0 +1,184 @@
+package ui
+
+import (
+	"fmt"
+	"math/rand"
+
+	"sektron/filesystem"
+	"sektron/midi"
+	"sektron/sequencer"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+const (
+	maxSeed          = 10000
+	maxMidiValue     = 127
+	maxPercentage    = 100
+	percentageStep   = 5
+	minLengthValue   = 2
+	randomLengthStep = pulsesPerStep / 2
+)
+
+var noteNames = []string{"C", "C#", "D", "D#", "E", "F", "F#", "G", "G#", "A", "A#", "B"}
+
+// randomizerParameter represents a randomizer constraint that can be
+// displayed and edited in the parameters carousel.
+type randomizerParameter struct {
+	string func(r filesystem.Randomizer) string
+	set    func(r *filesystem.Randomizer, add int)
+}
+
+func (m *mainModel) initRandomizerParameters() {
+	m.randomizerParams = []randomizerParameter{
+		{
+			string: func(r filesystem.Randomizer) string {
+				return fontParameter(fmt.Sprintf("%d%%", r.Density), "density")
+			},
+			set: func(r *filesystem.Randomizer, add int) {
+				r.Density = clamp(r.Density+add*percentageStep, 0, maxPercentage)
+			},
+		},
+		{
+			string: func(r filesystem.Randomizer) string {
+				return textParameter(noteNames[r.Root%12], "root")
+			},
+			set: func(r *filesystem.Randomizer, add int) {
+				r.Root = uint8((int(r.Root) + add + 12) % 12)
+			},
+		},
+		{
+			string: func(r filesystem.Randomizer) string {
+				return textParameter(r.Scale, "scale")
+			},
+			set: func(r *filesystem.Randomizer, add int) {
+				scales := sequencer.Scales()
+				current := 0
+				for i, s := range scales {
+					if s == r.Scale {
+						current = i
+					}
+				}
+				r.Scale = scales[(current+add+len(scales))%len(scales)]
+			},
+		},
+		{
+			string: func(r filesystem.Randomizer) string {
+				return fontParameter(midi.Note(r.MinNote), "min note")
+			},
+			set: func(r *filesystem.Randomizer, add int) {
+				r.MinNote = uint8(clamp(int(r.MinNote)+add, 0, maxMidiValue))
+			},
+		},
+		{
+			string: func(r filesystem.Randomizer) string {
+				return fontParameter(midi.Note(r.MaxNote), "max note")
+			},
+			set: func(r *filesystem.Randomizer, add int) {
+				r.MaxNote = uint8(clamp(int(r.MaxNote)+add, 0, maxMidiValue))
+			},
+		},
+		{
+			string: func(r filesystem.Randomizer) string {
+				return fontParameter(fmt.Sprintf("%d", r.MinVelocity), "min velocity")
+			},
+			set: func(r *filesystem.Randomizer, add int) {
+				r.MinVelocity = uint8(clamp(int(r.MinVelocity)+add, 0, maxMidiValue))
+			},
+		},
+		{
+			string: func(r filesystem.Randomizer) string {
+				return fontParameter(fmt.Sprintf("%d", r.MaxVelocity), "max velocity")
+			},
+			set: func(r *filesystem.Randomizer, add int) {
+				r.MaxVelocity = uint8(clamp(int(r.MaxVelocity)+add, 0, maxMidiValue))
+			},
+		},
+		{
+			string: func(r filesystem.Randomizer) string {
+				return fontParameter(fmt.Sprintf("%.1f", float64(r.MinLength)/pulsesPerStep), "min length")
+			},
+			set: func(r *filesystem.Randomizer, add int) {
+				r.MinLength = clamp(r.MinLength+add*randomLengthStep, minLengthValue, pulsesPerStep*maxSteps)
+			},
+		},
+		{
+			string: func(r filesystem.Randomizer) string {
+				return fontParameter(fmt.Sprintf("%.1f", float64(r.MaxLength)/pulsesPerStep), "max length")
+			},
+			set: func(r *filesystem.Randomizer, add int) {
+				r.MaxLength = clamp(r.MaxLength+add*randomLengthStep, minLengthValue, pulsesPerStep*maxSteps)
+			},
+		},
+		{
+			string: func(r filesystem.Randomizer) string {
+				return fontParameter(fmt.Sprintf("%d%%", r.MinProbability), "min probability")
+			},
+			set: func(r *filesystem.Randomizer, add int) {
+				r.MinProbability = clamp(r.MinProbability+add*percentageStep, 0, maxPercentage)
+			},
+		},
+		{
+			string: func(r filesystem.Randomizer) string {
+				return fontParameter(fmt.Sprintf("%d%%", r.MaxProbability), "max probability")
+			},
+			set: func(r *filesystem.Randomizer, add int) {
+				r.MaxProbability = clamp(r.MaxProbability+add*percentageStep, 0, maxPercentage)
+			},
+		},
+		{
+			string: func(r filesystem.Randomizer) string {
+				return fontParameter(fmt.Sprintf("%d", r.Seed), "seed")
+			},
+			set: func(r *filesystem.Randomizer, add int) {
+				r.Seed = int64(clamp(int(r.Seed)+add, 0, maxSeed-1))
+			},
+		},
+	}
+}
+
+// randomize generates random steps on the active track and saves the
+// randomizer constraints, so that the result can be recalled later on.
+func (m *mainModel) randomize() {
+	m.seq.Randomize(m.activeTrack, m.config.Randomizer)
+	m.config.Save()
+	m.updateSelection()
+	m.setStatus(fmt.Sprintf("track %d randomized (seed %d)", m.activeTrack+1, m.config.Randomizer.Seed))
+}
+
+// randomizeWithNewSeed picks a new random seed before generating steps.
+func (m *mainModel) randomizeWithNewSeed() {
+	m.config.Randomizer.Seed = rand.Int63n(maxSeed)
+	m.randomize()
+}
+
+func (m mainModel) randomizerParamsString() []string {
+	var params []string
+	for _, p := range m.randomizerParams {
+		params = append(params, p.string(m.config.Randomizer))
+	}
+	return params
+}
+
+func fontParameter(value, name string) string {
+	return lipgloss.JoinVertical(
+		lipgloss.Center,
+		toASCIIFont(value),
+		"",
+		name,
+	)
+}
+
+func textParameter(value, name string) string {
+	return lipgloss.JoinVertical(
+		lipgloss.Center,
+		"",
+		value,
+		"",
+		name,
+	)
+}
+
+func clamp(value, minValue, maxValue int) int {
+	return max(minValue, min(value, maxValue))
+}
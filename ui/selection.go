@@ -0,0 +1,178 @@
+package ui
+
+import (
+	"fmt"
+
+	"sektron/sequencer"
+)
+
+const maxSelectionInterval = 8
+
+// selectionMode represents the different ways of selecting multiple steps.
+type selectionMode uint8
+
+const (
+	// noSelection means that only the active step is edited.
+	noSelection selectionMode = iota
+
+	// rangeSelection selects all steps between an anchor and the active step.
+	rangeSelection
+
+	// intervalSelection selects every nth step, starting from the active
+	// step.
+	intervalSelection
+
+	// activeSelection selects all active steps of the track.
+	activeSelection
+)
+
+// selection holds the steps selected for bulk parameter editing. In relative
+// mode, each selected step value is increased or decreased from its own
+// value. In absolute mode, all selected steps are set to the active step
+// value.
+type selection struct {
+	mode     selectionMode
+	steps    []int
+	anchor   int
+	interval int
+	absolute bool
+}
+
+func (s selection) isActive() bool {
+	return s.mode != noSelection
+}
+
+func (s selection) contains(step int) bool {
+	if !s.isActive() {
+		return false
+	}
+	for _, stp := range s.steps {
+		if stp == step {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *selection) clear() {
+	s.mode = noSelection
+	s.steps = nil
+	s.interval = 0
+}
+
+// selectRange starts a new range selection from the active step. Moving the
+// active step then extends the selection.
+func (m *mainModel) selectRange() {
+	m.selection.clear()
+	m.selection.mode = rangeSelection
+	m.selection.anchor = m.activeStep
+	m.updateSelection()
+}
+
+// selectInterval selects every nth step, starting from the active step. Each
+// call increases the interval, until the selection is cleared.
+func (m *mainModel) selectInterval() {
+	interval := m.selection.interval + 1
+	if m.selection.mode != intervalSelection {
+		interval = 2
+	}
+	m.selection.clear()
+	if interval > maxSelectionInterval {
+		return
+	}
+	m.selection.mode = intervalSelection
+	m.selection.interval = interval
+	m.updateSelection()
+}
+
+// selectActiveSteps selects all the active steps of the active track.
+func (m *mainModel) selectActiveSteps() {
+	m.selection.clear()
+	m.selection.mode = activeSelection
+	m.updateSelection()
+}
+
+// updateSelection computes the selected steps from the selection mode and the
+// active step.
+func (m *mainModel) updateSelection() {
+	steps := m.getActiveTrack().Steps()
+	m.selection.steps = nil
+	switch m.selection.mode {
+	case rangeSelection:
+		first, last := min(m.selection.anchor, m.activeStep), max(m.selection.anchor, m.activeStep)
+		for i := first; i <= last && i < len(steps); i++ {
+			m.selection.steps = append(m.selection.steps, i)
+		}
+	case intervalSelection:
+		for i := m.activeStep; i < len(steps); i += m.selection.interval {
+			m.selection.steps = append(m.selection.steps, i)
+		}
+	case activeSelection:
+		for i, step := range steps {
+			if step.IsActive() {
+				m.selection.steps = append(m.selection.steps, i)
+			}
+		}
+	}
+}
+
+// validateSelection clears the selection if it contains steps that don't
+// exist anymore.
+func (m *mainModel) validateSelection() {
+	for _, stp := range m.selection.steps {
+		if stp >= len(m.getActiveTrack().Steps()) {
+			m.selection.clear()
+			return
+		}
+	}
+}
+
+// selectedSteps returns all the selected steps that are active. Inactive
+// steps can't be edited.
+func (m mainModel) selectedSteps() []sequencer.Step {
+	var steps []sequencer.Step
+	for _, stp := range m.selection.steps {
+		step := m.getActiveTrack().Steps()[stp]
+		if step.IsActive() {
+			steps = append(steps, step)
+		}
+	}
+	return steps
+}
+
+// getReferenceStep returns the step from which the parameters are displayed
+// and edited: the active step, or the first active selected step if the
+// active step is inactive.
+func (m mainModel) getReferenceStep() sequencer.Step {
+	step := m.getActiveStep()
+	if step.IsActive() || !m.selection.isActive() {
+		return step
+	}
+	if steps := m.selectedSteps(); len(steps) > 0 {
+		return steps[0]
+	}
+	return step
+}
+
+// editSelection increases or decreases the active parameter of all the
+// selected steps at once.
+func (m *mainModel) editSelection(add int) {
+	steps := m.selectedSteps()
+	if len(steps) == 0 {
+		return
+	}
+	p := m.parameters.getStepParam(m.getActiveParam())
+	ref := m.getReferenceStep()
+	description := fmt.Sprintf("track %d %s edit of %d steps", m.activeTrack+1, p.name, len(steps))
+	m.seq.Edit(description, func() {
+		p.setAll(ref, steps, m.selection.absolute, add)
+	})
+}
+
+func (m mainModel) selectionString() string {
+	mode := "rel"
+	if m.selection.absolute {
+		mode = "abs"
+	}
+	return fmt.Sprintf("%d steps (%s)", len(m.selection.steps), mode)
+}
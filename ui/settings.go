@@ -0,0 +1,253 @@
+package ui
+
+import (
+	"fmt"
+	"slices"
+
+	"sektron/filesystem"
+	"sektron/midi"
+
+	"github.com/muesli/reflow/wordwrap"
+)
+
+const (
+	maxClockRatio  = 8
+	maxClockOffset = 100
+	maxCountIn     = 2
+	maxChannel     = 15
+
+	metronomeDeviceParam = 2
+)
+
+// timeCodeRates holds the MIDI Time Code frame rates that can be selected
+// for a clock output, from off.
+var timeCodeRates = []string{"", "24", "25", "29.97", "30"}
+
+// settingsParameter represents a clock output setting that can be displayed
+// and edited in the parameters carousel.
+type settingsParameter struct {
+	string func(o filesystem.ClockOutput) string
+	set    func(o *filesystem.ClockOutput, add int)
+}
+
+// metronomeParameter represents a metronome setting that can be displayed and
+// edited in the parameters carousel, after the clock settings.
+type metronomeParameter struct {
+	string func(mt filesystem.Metronome) string
+	set    func(mt *filesystem.Metronome, add int)
+}
+
+func (m *mainModel) initSettingsParameters() {
+	m.settingsParams = []settingsParameter{
+		{
+			// The first parameter selects the device, it's handled by
+			// setSettingsParam.
+			string: func(o filesystem.ClockOutput) string {
+				device := o.Device
+				if len(device) > 30 {
+					device = device[:27] + "..."
+				}
+				return textParameter(wordwrap.String(device, 20), "device")
+			},
+		},
+		{
+			string: func(o filesystem.ClockOutput) string {
+				return textParameter(onOffString(o.Clock), "clock")
+			},
+			set: func(o *filesystem.ClockOutput, add int) {
+				o.Clock = add > 0
+			},
+		},
+		{
+			string: func(o filesystem.ClockOutput) string {
+				return textParameter(onOffString(o.Transport), "transport")
+			},
+			set: func(o *filesystem.ClockOutput, add int) {
+				o.Transport = add > 0
+			},
+		},
+		{
+			string: func(o filesystem.ClockOutput) string {
+				if o.TimeCode == "" {
+					return textParameter("off", "timecode fps")
+				}
+				return textParameter(o.TimeCode, "timecode fps")
+			},
+			set: func(o *filesystem.ClockOutput, add int) {
+				rate := slices.Index(timeCodeRates, o.TimeCode) + add
+				o.TimeCode = timeCodeRates[clamp(rate, 0, len(timeCodeRates)-1)]
+			},
+		},
+		{
+			string: func(o filesystem.ClockOutput) string {
+				return fontParameter(fmt.Sprintf("/%d", o.Divider), "divider")
+			},
+			set: func(o *filesystem.ClockOutput, add int) {
+				o.Divider = clamp(o.Divider+add, 1, maxClockRatio)
+			},
+		},
+		{
+			string: func(o filesystem.ClockOutput) string {
+				return fontParameter(fmt.Sprintf("%d", o.Multiplier), "multiplier")
+			},
+			set: func(o *filesystem.ClockOutput, add int) {
+				o.Multiplier = clamp(o.Multiplier+add, 1, maxClockRatio)
+			},
+		},
+		{
+			string: func(o filesystem.ClockOutput) string {
+				return fontParameter(fmt.Sprintf("%d", o.Offset), "offset ms")
+			},
+			set: func(o *filesystem.ClockOutput, add int) {
+				o.Offset = clamp(o.Offset+add, 0, maxClockOffset)
+			},
+		},
+	}
+
+	m.metronomeParams = []metronomeParameter{
+		{
+			string: func(mt filesystem.Metronome) string {
+				return textParameter(onOffString(mt.Enabled), "metronome")
+			},
+			set: func(mt *filesystem.Metronome, add int) {
+				mt.Enabled = add > 0
+			},
+		},
+		{
+			string: func(mt filesystem.Metronome) string {
+				if mt.CountIn == 0 {
+					return textParameter("off", "count-in bars")
+				}
+				return fontParameter(fmt.Sprintf("%d", mt.CountIn), "count-in bars")
+			},
+			set: func(mt *filesystem.Metronome, add int) {
+				mt.CountIn = clamp(mt.CountIn+add, 0, maxCountIn)
+			},
+		},
+		{
+			// The click device is handled by setSettingsParam.
+			string: func(mt filesystem.Metronome) string {
+				device := mt.Device
+				if device == "" {
+					device = "-"
+				}
+				if len(device) > 30 {
+					device = device[:27] + "..."
+				}
+				return textParameter(wordwrap.String(device, 20), "click device")
+			},
+		},
+		{
+			string: func(mt filesystem.Metronome) string {
+				return fontParameter(fmt.Sprintf("%d", mt.Channel+1), "click channel")
+			},
+			set: func(mt *filesystem.Metronome, add int) {
+				mt.Channel = uint8(clamp(int(mt.Channel)+add, 0, maxChannel))
+			},
+		},
+		{
+			string: func(mt filesystem.Metronome) string {
+				return fontParameter(midi.Note(mt.Note), "click note")
+			},
+			set: func(mt *filesystem.Metronome, add int) {
+				mt.Note = uint8(clamp(int(mt.Note)+add, 0, maxMidiValue))
+			},
+		},
+		{
+			string: func(mt filesystem.Metronome) string {
+				return fontParameter(midi.Note(mt.AccentNote), "accent note")
+			},
+			set: func(mt *filesystem.Metronome, add int) {
+				mt.AccentNote = uint8(clamp(int(mt.AccentNote)+add, 0, maxMidiValue))
+			},
+		},
+		{
+			string: func(mt filesystem.Metronome) string {
+				return fontParameter(fmt.Sprintf("%d", mt.Velocity), "click velocity")
+			},
+			set: func(mt *filesystem.Metronome, add int) {
+				mt.Velocity = uint8(clamp(int(mt.Velocity)+add, 0, maxMidiValue))
+			},
+		},
+	}
+}
+
+// clockOutput returns the clock settings of the selected device and their
+// index in the configuration, or -1 if the device has no settings yet.
+func (m mainModel) clockOutput() (filesystem.ClockOutput, int) {
+	devices := m.seq.OutputDevices()
+	if m.activeSettingsDevice >= len(devices) {
+		return filesystem.ClockOutput{}, -1
+	}
+	device := devices[m.activeSettingsDevice]
+	for i, output := range m.config.Clock.Outputs {
+		if output.Device == device {
+			return output, i
+		}
+	}
+	return filesystem.NewClockOutput(device, false), -1
+}
+
+func (m mainModel) settingsParamsString() []string {
+	output, _ := m.clockOutput()
+	var params []string
+	for _, p := range m.settingsParams {
+		params = append(params, p.string(output))
+	}
+	for _, p := range m.metronomeParams {
+		params = append(params, p.string(m.config.Metronome))
+	}
+	return params
+}
+
+// setSettingsParam increases or decreases the selected setting, then saves
+// the configuration and applies it.
+func (m *mainModel) setSettingsParam(add int) {
+	if m.activeSettingsParam >= len(m.settingsParams) {
+		m.setMetronomeParam(m.activeSettingsParam-len(m.settingsParams), add)
+		return
+	}
+	if m.activeSettingsParam == 0 {
+		m.activeSettingsDevice = clamp(m.activeSettingsDevice+add, 0, len(m.seq.OutputDevices())-1)
+		return
+	}
+
+	output, index := m.clockOutput()
+	if output.Device == "" {
+		return
+	}
+	m.settingsParams[m.activeSettingsParam].set(&output, add)
+	if index < 0 {
+		m.config.Clock.Outputs = append(m.config.Clock.Outputs, output)
+	} else {
+		m.config.Clock.Outputs[index] = output
+	}
+	m.config.Save()
+	m.seq.SetClockOutputs(m.config.Clock.Outputs)
+}
+
+// setMetronomeParam increases or decreases the given metronome setting.
+// The click device param selects the next or previous device.
+func (m *mainModel) setMetronomeParam(param, add int) {
+	if param == metronomeDeviceParam {
+		devices := m.seq.OutputDevices()
+		current := 0
+		for i, device := range devices {
+			if device == m.config.Metronome.Device {
+				current = i
+			}
+		}
+		m.config.Metronome.Device = devices[clamp(current+add, 0, len(devices)-1)]
+	} else {
+		m.metronomeParams[param].set(&m.config.Metronome, add)
+	}
+	m.config.Save()
+	m.seq.SetMetronome(m.config.Metronome)
+}
+
+func onOffString(value bool) string {
+	if value {
+		return "on"
+	}
+	return "off"
+}
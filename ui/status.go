@@ -0,0 +1,43 @@
+package ui
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+var statusStyle = lipgloss.NewStyle().
+	Foreground(secondaryColor).
+	Bold(true)
+
+// setStatus displays a short message to the user for a few seconds.
+func (m *mainModel) setStatus(status string) {
+	m.status = status
+	m.statusTimer = statusTimeout
+}
+
+// setHistoryStatus displays the result of an undo or redo action.
+func (m *mainModel) setHistoryStatus(action, description string) {
+	if description == "" {
+		m.setStatus(fmt.Sprintf("nothing to %s", action))
+		return
+	}
+	m.setStatus(fmt.Sprintf("%s: %s", action, description))
+}
+
+// setClockStatsStatus displays the clock timing statistics.
+func (m *mainModel) setClockStatsStatus() {
+	stats := m.seq.ClockStats()
+	m.setStatus(fmt.Sprintf(
+		"clock jitter over %d pulses: mean %s, max %s, deviation %s",
+		stats.Pulses,
+		stats.Mean.Round(time.Microsecond),
+		stats.Max.Round(time.Microsecond),
+		stats.Deviation.Round(time.Microsecond),
+	))
+}
+
+func (m mainModel) renderStatus() string {
+	return statusStyle.Render(m.status)
+}
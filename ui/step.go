@@ -40,6 +40,10 @@ func (m mainModel) renderStep(step sequencer.Step) string {
 		stepCurrentColor = currentColor
 		stepActiveColor = primaryColor
 		stepInactiveColor = primaryColor
+	} else if m.mode == stepMode && m.selection.contains(step.Position()) {
+		stepCurrentColor = currentColor
+		stepActiveColor = secondaryColor
+		stepInactiveColor = secondaryColor
 	} else if step.Track().IsActive() {
 		stepCurrentColor = currentColor
 		stepActiveColor = activeColor
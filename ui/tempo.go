@@ -0,0 +1,52 @@
+package ui
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const (
+	fineTempoStep     = 0.1
+	maxTempoInputSize = 5
+)
+
+// setTempo sets the sequencer tempo, rounded to the first decimal.
+func (m *mainModel) setTempo(tempo float64) {
+	m.seq.SetTempo(math.Round(tempo*10) / 10)
+}
+
+// updateTempoEntry handles the keys while the user is typing a new tempo.
+// Enter validates the new tempo, escape cancels.
+func (m *mainModel) updateTempoEntry(msg tea.KeyMsg) {
+	switch {
+	case key.Matches(msg, m.keymap.Validate):
+		m.tempoEntry = false
+		tempo, err := strconv.ParseFloat(m.tempoInput, 64)
+		if err != nil {
+			return
+		}
+		m.setTempo(tempo)
+	case key.Matches(msg, m.keymap.Quit):
+		m.tempoEntry = false
+	case msg.Type == tea.KeyBackspace:
+		if len(m.tempoInput) > 0 {
+			m.tempoInput = m.tempoInput[:len(m.tempoInput)-1]
+		}
+	case msg.Type == tea.KeyRunes && len(msg.Runes) == 1:
+		r := msg.Runes[0]
+		if (r >= '0' && r <= '9' || r == '.') && len(m.tempoInput) < maxTempoInputSize {
+			m.tempoInput += string(r)
+		}
+	}
+}
+
+func tempoString(tempo float64) string {
+	if tempo == math.Trunc(tempo) {
+		return fmt.Sprintf("%.0f", tempo)
+	}
+	return fmt.Sprintf("%.1f", tempo)
+}
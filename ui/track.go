@@ -43,3 +43,10 @@ func (m mainModel) trackPagesNb() int {
 	}
 	return pageNb
 }
+
+// activePageRange returns the first and last steps of the active track page.
+func (m mainModel) activePageRange() (int, int) {
+	first := m.activeTrackPage * stepsPerPage
+	last := min(first+stepsPerPage, len(m.getActiveTrack().Steps())) - 1
+	return first, last
+}
@@ -110,7 +110,26 @@ func (m mainModel) renderTransportTracks() string {
 }
 
 func (m mainModel) renderTransportTempo() string {
-	text := fmt.Sprintf("⧗ %.0f", m.seq.Tempo())
+	if m.tempoEntry {
+		return tempoStyle.Render(fmt.Sprintf("⧗ %s_", m.tempoInput))
+	}
+	text := fmt.Sprintf("⧗ %s", tempoString(m.seq.Tempo()))
+	if tempo, ok := m.seq.ExternalTempo(); ok {
+		// The estimated tempo is not rounded, we show one decimal.
+		text = "⧗ ext ---"
+		if tempo > 0 {
+			text = fmt.Sprintf("⧗ ext %.1f", tempo)
+		}
+	} else if target, ok := m.seq.IsRamping(); ok {
+		arrow := "↘"
+		if target > m.seq.Tempo() {
+			arrow = "↗"
+		}
+		text = fmt.Sprintf("⧗ %.1f %s %s", m.seq.Tempo(), arrow, tempoString(target))
+	}
+	if peers, ok := m.seq.LinkPeers(); ok {
+		text = fmt.Sprintf("%s link %d", text, peers)
+	}
 	if m.isActiveTrackOnQuarterNote() {
 		return tempoTickStyle.Render(text)
 	}
@@ -118,6 +137,9 @@ func (m mainModel) renderTransportTempo() string {
 }
 
 func (m mainModel) renderTransportPlayer() string {
+	if beats, ok := m.seq.CountIn(); ok {
+		return transportPlayingStyle.Render(fmt.Sprintf("▶ %d", beats))
+	}
 	if m.seq.IsPlaying() {
 		return transportPlayingStyle.Render("▶")
 	}
@@ -5,6 +5,7 @@
 package ui
 
 import (
+	"fmt"
 	"time"
 
 	"sektron/filesystem"
@@ -38,6 +39,14 @@ const (
 
 	// paramSelectMode allows the user to add new midi controls to the track.
 	paramSelectMode
+
+	// randomizeMode allows the user to set the randomizer constraints and
+	// generate random steps on the track.
+	randomizeMode
+
+	// settingsMode allows the user to set which midi devices receive the
+	// clock and how.
+	settingsMode
 )
 
 const (
@@ -46,40 +55,73 @@ const (
 	refreshFrequency = 33 * time.Millisecond
 
 	stepModeTimeout = 40
+	statusTimeout   = 60
 )
 
 type mainModel struct {
-	seq               sequencer.Sequencer
-	parameters        parameters
-	paramCarousel     carousel.Model
-	paramMidiTable    table.Model
-	keymap            keyMap
-	width             int
-	height            int
-	mode              mode
-	activeTrack       int
-	activeTrackPage   int
-	activeStep        int
-	activeParams      []struct{ track, step int }
-	activePatternPage int
-	stepModeTimer     int
-	help              help.Model
+	seq                  sequencer.Sequencer
+	config               *filesystem.Configuration
+	parameters           parameters
+	randomizerParams     []randomizerParameter
+	settingsParams       []settingsParameter
+	metronomeParams      []metronomeParameter
+	paramCarousel        carousel.Model
+	paramMidiTable       table.Model
+	keymap               keyMap
+	width                int
+	height               int
+	mode                 mode
+	activeTrack          int
+	activeTrackPage      int
+	activeStep           int
+	activeParams         []struct{ track, step int }
+	activeRandomParam    int
+	activePatternParam   int
+	activeSettingsParam  int
+	activeSettingsDevice int
+	activePatternPage    int
+	selection            selection
+	stepModeTimer        int
+	tempoEntry           bool
+	tempoInput           string
+	status               string
+	statusTimer          int
+	help                 help.Model
+	learn                learnState
+
+	// When attached to a running engine, quitting only detaches the ui.
+	detachable bool
 }
 
 // New creates a new mainModel that hols the ui state. It takes a new sequencer.
-// Check teh sequencer package.
-func New(config filesystem.Configuration, seq sequencer.Sequencer) tea.Model {
+// Check teh sequencer package. The configuration is only read and saved
+// while holding the sequencer lock, so that it can be shared by several uis.
+func New(config *filesystem.Configuration, seq sequencer.Sequencer) tea.Model {
+	seq.Lock()
+	defer seq.Unlock()
 	model := mainModel{
 		seq:          seq,
+		config:       config,
 		keymap:       newKeyMap(config.KeyMap),
 		activeParams: make([]struct{ track, step int }, 10),
 		help:         help.New(),
 	}
+	model.initRandomizerParameters()
+	model.initSettingsParameters()
 	model.initParameters()
 	model.initMidiControls()
 	return model
 }
 
+// NewDetachable creates a new mainModel for a ui attached to a running engine
+// (check the engine package). Quitting detaches the ui, while the sequencer
+// keeps playing.
+func NewDetachable(config *filesystem.Configuration, seq sequencer.Sequencer) tea.Model {
+	model := New(config, seq).(mainModel)
+	model.detachable = true
+	return model
+}
+
 func tick() tea.Cmd {
 	return tea.Tick(refreshFrequency, func(t time.Time) tea.Msg {
 		return tickMsg(t)
@@ -90,7 +132,15 @@ func (m mainModel) Init() tea.Cmd {
 	return tea.Batch(tea.EnterAltScreen, tick())
 }
 
+// Update and View hold the sequencer lock, as the sequencer is also updated
+// by its own goroutines and the other uis and servers.
 func (m mainModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	m.seq.Lock()
+	defer m.seq.Unlock()
+	return m.update(msg)
+}
+
+func (m mainModel) update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	m.resetPatternState()
 
 	switch msg := msg.(type) {
@@ -105,7 +155,8 @@ func (m mainModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, nil
 
 	case tickMsg:
-		if m.mode == stepMode {
+		// We stay in step mode as long as steps are selected.
+		if m.mode == stepMode && !m.selection.isActive() {
 			m.stepModeTimer++
 		}
 		if m.stepModeTimer > stepModeTimeout {
@@ -113,9 +164,31 @@ func (m mainModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.mode = trackMode
 			m.updateParams()
 		}
+		if m.statusTimer > 0 {
+			m.statusTimer--
+			if m.statusTimer == 0 {
+				m.status = ""
+			}
+		}
 		return m, tick()
 
+	case ControllerMsg:
+		return m.receiveController(msg)
+
 	case tea.KeyMsg:
+		if m.tempoEntry {
+			m.updateTempoEntry(msg)
+			return m, nil
+		}
+		if key.Matches(msg, m.keymap.MidiLearn) {
+			m.toggleLearn()
+			return m, nil
+		}
+		if m.learn.msg != nil {
+			m.learnKey(msg)
+			return m, nil
+		}
+
 		switch {
 		case key.Matches(msg, m.keymap.Play):
 			m.seq.TogglePlay()
@@ -123,6 +196,7 @@ func (m mainModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 		case key.Matches(msg, m.keymap.ParamMode):
 			m.activeStep = 0
+			m.selection.clear()
 			if m.mode == trackMode {
 				m.mode = stepMode
 			} else {
@@ -132,6 +206,7 @@ func (m mainModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m, nil
 
 		case key.Matches(msg, m.keymap.PatternMode):
+			m.selection.clear()
 			if m.mode == patternMode {
 				m.mode = trackMode
 			} else {
@@ -147,6 +222,7 @@ func (m mainModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case key.Matches(msg, m.keymap.RemoveTrack):
 			if m.activeTrack > 0 && m.activeTrack == len(m.seq.Tracks())-1 {
 				m.activeTrack--
+				m.seq.SetThruTrack(m.activeTrack)
 			}
 			m.seq.RemoveTrack()
 			return m, nil
@@ -162,6 +238,8 @@ func (m mainModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 			m.activeStep = 0
 			m.seq.RemoveStep(m.activeTrack)
+			m.validateSelection()
+			m.updateSelection()
 			return m, nil
 
 		case key.Matches(msg, m.keymap.PreviousStep):
@@ -174,6 +252,7 @@ func (m mainModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.activeStep = newIndex
 				// Paginate if needed
 				m.activeTrackPage = (newIndex / stepsPerPage)
+				m.updateSelection()
 
 				m.mode = stepMode
 				m.stepModeTimer = 0
@@ -190,6 +269,7 @@ func (m mainModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.activeStep = newIndex
 				// Paginate if needed
 				m.activeTrackPage = (newIndex / stepsPerPage)
+				m.updateSelection()
 
 				m.mode = stepMode
 				m.stepModeTimer = 0
@@ -207,18 +287,31 @@ func (m mainModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					m.seq.Save()
 					m.seq.Load(pattern)
 				}
+				m.updateParams()
 				return m, nil
 			}
 			if number >= len(m.getActiveTrack().Steps())-(m.activeTrackPage*stepsPerPage) {
 				return m, nil
 			}
 			m.activeStep = number + (m.activeTrackPage * stepsPerPage)
+			m.updateSelection()
 			m.mode = stepMode
 			m.stepModeTimer = 0
 			m.updateParams()
 			return m, nil
 
 		case key.Matches(msg, m.keymap.CopyStep):
+			switch m.mode {
+			case patternMode:
+				m.seq.CopyPattern(m.seq.ActivePattern())
+				m.setStatus(fmt.Sprintf("pattern %d copied", m.seq.ActivePattern()+1))
+				return m, nil
+			case trackMode:
+				first, last := m.activePageRange()
+				m.seq.CopySteps(m.activeTrack, first, last)
+				m.setStatus(fmt.Sprintf("steps %d-%d of track %d copied", first+1, last+1, m.activeTrack+1))
+				return m, nil
+			}
 			m.seq.CopyStep(m.activeTrack, m.activeStep)
 			m.mode = stepMode
 			m.stepModeTimer = 0
@@ -226,12 +319,155 @@ func (m mainModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m, nil
 
 		case key.Matches(msg, m.keymap.PasteStep):
+			switch m.mode {
+			case patternMode:
+				m.seq.PastePattern(m.seq.ActivePattern())
+				m.resetPatternState()
+				m.updateParams()
+				return m, nil
+			case trackMode:
+				first, _ := m.activePageRange()
+				m.seq.PasteSteps(m.activeTrack, first)
+				return m, nil
+			}
 			m.seq.PasteStep(m.activeTrack, m.activeStep)
 			m.mode = stepMode
 			m.stepModeTimer = 0
 			m.updateParams()
 			return m, nil
 
+		case key.Matches(msg, m.keymap.ClearStep):
+			switch m.mode {
+			case patternMode:
+				m.seq.ClearPattern(m.seq.ActivePattern())
+				m.resetPatternState()
+				m.updateParams()
+				return m, nil
+			case trackMode:
+				first, last := m.activePageRange()
+				m.seq.ClearSteps(m.activeTrack, first, last)
+				return m, nil
+			}
+			m.seq.ClearSteps(m.activeTrack, m.activeStep, m.activeStep)
+			m.stepModeTimer = 0
+			m.updateParams()
+			return m, nil
+
+		case key.Matches(msg, m.keymap.CopyTrack):
+			m.seq.CopyTrack(m.activeTrack)
+			m.setStatus(fmt.Sprintf("track %d copied", m.activeTrack+1))
+			return m, nil
+
+		case key.Matches(msg, m.keymap.PasteTrack):
+			m.seq.PasteTrack(m.activeTrack)
+			m.resetPatternState()
+			m.updateParams()
+			return m, nil
+
+		case key.Matches(msg, m.keymap.ClearTrack):
+			m.seq.ClearTrack(m.activeTrack)
+			m.updateParams()
+			return m, nil
+
+		case key.Matches(msg, m.keymap.RotateLeft):
+			m.seq.RotateTrack(m.activeTrack, -1)
+			m.updateSelection()
+			m.updateParams()
+			return m, nil
+
+		case key.Matches(msg, m.keymap.RotateRight):
+			m.seq.RotateTrack(m.activeTrack, 1)
+			m.updateSelection()
+			m.updateParams()
+			return m, nil
+
+		case key.Matches(msg, m.keymap.Reverse):
+			m.seq.ReverseTrack(m.activeTrack)
+			m.updateSelection()
+			m.updateParams()
+			return m, nil
+
+		case key.Matches(msg, m.keymap.Invert):
+			m.seq.InvertTrack(m.activeTrack)
+			m.updateSelection()
+			m.updateParams()
+			return m, nil
+
+		case key.Matches(msg, m.keymap.Double):
+			m.seq.DoubleTrack(m.activeTrack)
+			m.updateParams()
+			return m, nil
+
+		case key.Matches(msg, m.keymap.Halve):
+			m.seq.HalveTrack(m.activeTrack)
+			if m.activeStep >= len(m.getActiveTrack().Steps()) {
+				m.activeStep = 0
+			}
+			if m.activeTrackPage >= m.trackPagesNb() {
+				m.activeTrackPage = m.trackPagesNb() - 1
+			}
+			m.validateSelection()
+			m.updateParams()
+			return m, nil
+
+		case key.Matches(msg, m.keymap.Randomize):
+			if m.mode == randomizeMode {
+				m.randomizeWithNewSeed()
+			} else {
+				m.selection.clear()
+				m.mode = randomizeMode
+			}
+			m.updateParams()
+			return m, nil
+
+		case key.Matches(msg, m.keymap.Settings):
+			m.selection.clear()
+			if m.mode == settingsMode {
+				m.mode = trackMode
+			} else {
+				m.mode = settingsMode
+			}
+			m.updateParams()
+			return m, nil
+
+		case key.Matches(msg, m.keymap.SelectRange):
+			m.selectRange()
+			m.mode = stepMode
+			m.stepModeTimer = 0
+			m.updateParams()
+			return m, nil
+
+		case key.Matches(msg, m.keymap.SelectEvery):
+			m.selectInterval()
+			m.mode = stepMode
+			m.stepModeTimer = 0
+			m.updateParams()
+			return m, nil
+
+		case key.Matches(msg, m.keymap.SelectActive):
+			m.selectActiveSteps()
+			m.mode = stepMode
+			m.stepModeTimer = 0
+			m.updateParams()
+			return m, nil
+
+		case key.Matches(msg, m.keymap.EditMode):
+			m.selection.absolute = !m.selection.absolute
+			m.updateParams()
+			return m, nil
+
+		case key.Matches(msg, m.keymap.Undo):
+			m.setHistoryStatus("undo", m.seq.Undo())
+			m.resetPatternState()
+			m.updateParams()
+			return m, nil
+
+		case key.Matches(msg, m.keymap.Redo):
+			m.setHistoryStatus("redo", m.seq.Redo())
+			m.resetPatternState()
+			m.updateParams()
+			return m, nil
+
 		case key.Matches(msg, m.keymap.StepToggle):
 			number := m.keymap.StepToggleIndex[msg.String()]
 			if m.mode == patternMode {
@@ -244,6 +480,7 @@ func (m mainModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 			m.activeStep = number + (m.activeTrackPage * stepsPerPage)
 			m.seq.ToggleStep(m.activeTrack, m.activeStep)
+			m.updateSelection()
 			m.mode = stepMode
 			m.stepModeTimer = 0
 			m.updateParams()
@@ -257,7 +494,9 @@ func (m mainModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.activeTrack = number
 			m.activeTrackPage = 0
 			m.activeStep = 0
+			m.selection.clear()
 			m.mode = trackMode
+			m.seq.SetThruTrack(m.activeTrack)
 			m.updateParams()
 			return m, nil
 
@@ -300,6 +539,39 @@ func (m mainModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.seq.SetTempo(m.seq.Tempo() - 1)
 			return m, nil
 
+		case key.Matches(msg, m.keymap.FineTempoUp):
+			m.setTempo(m.seq.Tempo() + fineTempoStep)
+			return m, nil
+
+		case key.Matches(msg, m.keymap.FineTempoDown):
+			m.setTempo(m.seq.Tempo() - fineTempoStep)
+			return m, nil
+
+		case key.Matches(msg, m.keymap.TapTempo):
+			m.seq.TapTempo()
+			return m, nil
+
+		case key.Matches(msg, m.keymap.NudgeUp):
+			m.seq.Nudge(1)
+			return m, nil
+
+		case key.Matches(msg, m.keymap.NudgeDown):
+			m.seq.Nudge(-1)
+			return m, nil
+
+		case key.Matches(msg, m.keymap.TempoEntry):
+			m.tempoEntry = true
+			m.tempoInput = ""
+			return m, nil
+
+		case key.Matches(msg, m.keymap.ClockStats):
+			m.setClockStatsStatus()
+			return m, nil
+
+		case key.Matches(msg, m.keymap.Export):
+			m.exportChain()
+			return m, nil
+
 		case key.Matches(msg, m.keymap.AddParam):
 			m.mode = paramSelectMode
 			m.updateParams()
@@ -324,6 +596,11 @@ func (m mainModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 			if m.mode == stepMode {
 				m.seq.ToggleStep(m.activeTrack, m.activeStep)
+				m.updateSelection()
+			}
+			if m.mode == randomizeMode {
+				m.randomize()
+				m.updateParams()
 			}
 			return m, nil
 
@@ -342,7 +619,15 @@ func (m mainModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m, nil
 
 		case key.Matches(msg, m.keymap.Up):
-			if m.mode == stepMode && m.getActiveStep().IsActive() {
+			if m.mode == randomizeMode {
+				m.randomizerParams[m.activeRandomParam].set(&m.config.Randomizer, 1)
+			} else if m.mode == settingsMode {
+				m.setSettingsParam(1)
+			} else if m.mode == patternMode {
+				m.setPatternParam(1)
+			} else if m.mode == stepMode && m.selection.isActive() {
+				m.editSelection(1)
+			} else if m.mode == stepMode && m.getActiveStep().IsActive() {
 				m.parameters.getStepParam(m.getActiveParam()).increase(m.getActiveStep())
 			} else if m.mode == trackMode {
 				m.parameters.getTrackParam(m.getActiveParam()).increase(m.getActiveTrack())
@@ -357,7 +642,15 @@ func (m mainModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m, nil
 
 		case key.Matches(msg, m.keymap.Down):
-			if m.mode == stepMode && m.getActiveStep().IsActive() {
+			if m.mode == randomizeMode {
+				m.randomizerParams[m.activeRandomParam].set(&m.config.Randomizer, -1)
+			} else if m.mode == settingsMode {
+				m.setSettingsParam(-1)
+			} else if m.mode == patternMode {
+				m.setPatternParam(-1)
+			} else if m.mode == stepMode && m.selection.isActive() {
+				m.editSelection(-1)
+			} else if m.mode == stepMode && m.getActiveStep().IsActive() {
 				m.parameters.getStepParam(m.getActiveParam()).decrease(m.getActiveStep())
 			} else if m.mode == trackMode {
 				m.parameters.getTrackParam(m.getActiveParam()).decrease(m.getActiveTrack())
@@ -376,6 +669,10 @@ func (m mainModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m, tea.ClearScreen
 
 		case key.Matches(msg, m.keymap.Quit):
+			if m.detachable {
+				m.seq.Save()
+				return m, tea.Quit
+			}
 			if m.seq.IsPlaying() {
 				m.seq.TogglePlay()
 			}
@@ -388,6 +685,8 @@ func (m mainModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 }
 
 func (m mainModel) View() string {
+	m.seq.Lock()
+	defer m.seq.Unlock()
 	mainView := lipgloss.JoinVertical(
 		lipgloss.Left,
 		m.renderTransport(),
@@ -395,18 +694,20 @@ func (m mainModel) View() string {
 		m.renderParams(),
 	)
 
+	status := m.renderStatus()
 	help := m.help.View(m.keymap)
 
 	// Cleanup gibber
 	cleanup := lipgloss.NewStyle().
 		Width(m.width).
-		Height(m.height - lipgloss.Height(mainView) - lipgloss.Height(help)).
+		Height(m.height - lipgloss.Height(mainView) - lipgloss.Height(status) - lipgloss.Height(help)).
 		Render("")
 
 	return lipgloss.JoinVertical(
 		lipgloss.Left,
 		mainView,
 		cleanup,
+		status,
 		help,
 	)
 }
@@ -418,7 +719,10 @@ func (m *mainModel) resetPatternState() {
 		m.activeTrack = 0
 		m.activeTrackPage = 0
 		m.activeStep = 0
+		m.selection.clear()
+		m.seq.SetThruTrack(m.activeTrack)
 	}
+	m.validateSelection()
 }
 
 func (m *mainModel) getActiveTrack() sequencer.Track {
@@ -430,6 +734,15 @@ func (m *mainModel) getActiveStep() sequencer.Step {
 }
 
 func (m mainModel) getActiveParam() int {
+	if m.mode == randomizeMode {
+		return m.activeRandomParam
+	}
+	if m.mode == settingsMode {
+		return m.activeSettingsParam
+	}
+	if m.mode == patternMode {
+		return m.activePatternParam
+	}
 	if m.mode == stepMode {
 		return m.activeParams[m.activeTrack].step
 	}
@@ -438,7 +751,13 @@ func (m mainModel) getActiveParam() int {
 
 func (m *mainModel) nextParam() {
 	m.paramCarousel.MoveRight()
-	if m.mode == stepMode {
+	if m.mode == randomizeMode {
+		m.activeRandomParam = m.paramCarousel.Cursor()
+	} else if m.mode == settingsMode {
+		m.activeSettingsParam = m.paramCarousel.Cursor()
+	} else if m.mode == patternMode {
+		m.activePatternParam = m.paramCarousel.Cursor()
+	} else if m.mode == stepMode {
 		m.activeParams[m.activeTrack].step = m.paramCarousel.Cursor()
 	} else {
 		m.activeParams[m.activeTrack].track = m.paramCarousel.Cursor()
@@ -448,7 +767,13 @@ func (m *mainModel) nextParam() {
 
 func (m *mainModel) previousParam() {
 	m.paramCarousel.MoveLeft()
-	if m.mode == stepMode {
+	if m.mode == randomizeMode {
+		m.activeRandomParam = m.paramCarousel.Cursor()
+	} else if m.mode == settingsMode {
+		m.activeSettingsParam = m.paramCarousel.Cursor()
+	} else if m.mode == patternMode {
+		m.activePatternParam = m.paramCarousel.Cursor()
+	} else if m.mode == stepMode {
 		m.activeParams[m.activeTrack].step = m.paramCarousel.Cursor()
 	} else {
 		m.activeParams[m.activeTrack].track = m.paramCarousel.Cursor()